@@ -0,0 +1,63 @@
+package radius
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+)
+
+// ascendSecret implements the Ascend secret encryption, used by attributes
+// with the dictionary flag encrypt=3 such as Ascend-Send-Secret. The value,
+// of at most 16 bytes, is XORed with the MD5 hash of the Request
+// Authenticator followed by the shared secret.
+type ascendSecret struct{}
+
+func (ascendSecret) mask(p *Packet) []byte {
+	hash := md5.New()
+	hash.Write(p.Authenticator[:])
+	hash.Write(p.Secret)
+	return hash.Sum(nil)
+}
+
+func (c ascendSecret) Decode(p *Packet, value []byte) (interface{}, error) {
+	if p.Secret == nil {
+		return nil, errors.New("radius: Ascend secret attribute requires Packet.Secret")
+	}
+	if len(value) != md5.Size {
+		return nil, errors.New("radius: invalid Ascend secret attribute length")
+	}
+	v := make([]byte, md5.Size)
+	mask := c.mask(p)
+	for i := range v {
+		v[i] = value[i] ^ mask[i]
+	}
+	if i := bytes.IndexByte(v, 0); i > -1 {
+		return string(v[:i]), nil
+	}
+	return string(v), nil
+}
+
+func (c ascendSecret) Encode(p *Packet, value interface{}) ([]byte, error) {
+	if p.Secret == nil {
+		return nil, errors.New("radius: Ascend secret attribute requires Packet.Secret")
+	}
+	var secret []byte
+	switch v := value.(type) {
+	case string:
+		secret = []byte(v)
+	case []byte:
+		secret = v
+	default:
+		return nil, errors.New("radius: Ascend secret attribute must be string or []byte")
+	}
+	if len(secret) > md5.Size {
+		return nil, errors.New("radius: invalid Ascend secret attribute length")
+	}
+	v := make([]byte, md5.Size)
+	copy(v, secret)
+	mask := c.mask(p)
+	for i := range v {
+		v[i] ^= mask[i]
+	}
+	return v, nil
+}
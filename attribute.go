@@ -1,9 +1,24 @@
 package radius
 
 // Attribute is a RADIUS attribute, which is part of a RADIUS packet.
+//
+// Vendor-Specific attributes (type 26) whose vendor is registered in the
+// packet's Dictionary are stored with Vendor set to the vendor's ID and Type
+// set to the vendor-type. Vendor is zero for all other attributes.
+//
+// Attributes in the RFC 6929 extended attribute space (Type 241 to 246) have
+// ExtendedType set to their Extended-Type. Fragmented Long Extended Type
+// attributes are stored as a single Attribute.
+//
+// Invalid is set for attributes whose value could not be decoded by
+// ParseLenient. Their Value is the []byte of the attribute's wire value,
+// which is encoded unchanged.
 type Attribute struct {
-	Type  byte
-	Value interface{}
+	Vendor       uint32
+	Type         byte
+	ExtendedType byte
+	Value        interface{}
+	Invalid      bool
 }
 
 // AttributeCodec defines how an Attribute is encoded and decoded to and from
@@ -3,7 +3,9 @@ package radius
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"net"
+	"strconv"
 	"time"
 	"unicode/utf8"
 )
@@ -134,3 +136,99 @@ func (attributeTime) Encode(packet *Packet, value interface{}) ([]byte, error) {
 	binary.BigEndian.PutUint32(raw, uint32(timestamp.Unix()))
 	return raw, nil
 }
+
+// attributeEnum wraps an integer codec with the value names of an enumerated
+// attribute. Value names can be used wherever the integer value is accepted;
+// decoded values keep the type of the wrapped codec.
+type attributeEnum struct {
+	AttributeCodec
+	names  map[uint32]string
+	values map[string]uint32
+}
+
+// with returns a copy of e which includes the given value name.
+func (e *attributeEnum) with(name string, value uint32) *attributeEnum {
+	enum := &attributeEnum{
+		AttributeCodec: e.AttributeCodec,
+		names:          make(map[uint32]string, len(e.names)+1),
+		values:         make(map[string]uint32, len(e.values)+1),
+	}
+	for k, v := range e.names {
+		enum.names[k] = v
+	}
+	for k, v := range e.values {
+		enum.values[k] = v
+	}
+	// The first name registered for a value is used when printing it.
+	if _, ok := enum.names[value]; !ok {
+		enum.names[value] = name
+	}
+	enum.values[name] = value
+	return enum
+}
+
+func (e *attributeEnum) value(name string) (uint32, error) {
+	value, ok := e.values[name]
+	if !ok {
+		return 0, errors.New("radius: unknown attribute value name " + strconv.Quote(name))
+	}
+	return value, nil
+}
+
+func (e *attributeEnum) Transform(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		number, err := e.value(v)
+		if err != nil {
+			return nil, err
+		}
+		value = number
+	case Tagged:
+		if name, ok := v.Value.(string); ok {
+			number, err := e.value(name)
+			if err != nil {
+				return nil, err
+			}
+			v.Value = number
+			value = v
+		}
+	}
+	if transformer, ok := e.AttributeCodec.(AttributeTransformer); ok {
+		return transformer.Transform(value)
+	}
+	return value, nil
+}
+
+func (e *attributeEnum) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	value, err := e.Transform(value)
+	if err != nil {
+		return nil, err
+	}
+	return e.AttributeCodec.Encode(packet, value)
+}
+
+func (e *attributeEnum) String(value interface{}) string {
+	switch v := value.(type) {
+	case uint8:
+		value = uint32(v)
+	case uint16:
+		value = uint32(v)
+	}
+	switch v := value.(type) {
+	case uint32:
+		if name, ok := e.names[v]; ok {
+			return name
+		}
+		return strconv.FormatUint(uint64(v), 10)
+	case Tagged:
+		if number, ok := v.Value.(uint32); ok {
+			if name, ok := e.names[number]; ok {
+				return name
+			}
+		}
+	}
+	if stringer, ok := e.AttributeCodec.(AttributeStringer); ok {
+		return stringer.String(value)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,240 @@
+package radius
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+)
+
+// Additional attribute value formats, which are used by the data types of
+// dictionary files.
+var (
+	// uint8
+	AttributeByte AttributeCodec
+	// uint16
+	AttributeShort AttributeCodec
+	// int32; values of other integer types are converted
+	AttributeSigned AttributeCodec
+	// net.HardwareAddr
+	AttributeEther AttributeCodec
+	// []byte; Ascend binary filters are passed through as is
+	AttributeABinary AttributeCodec
+	// net.IP, an IPv4 or IPv6 address
+	AttributeComboIP AttributeCodec
+)
+
+func init() {
+	AttributeByte = attributeByte{}
+	AttributeShort = attributeShort{}
+	AttributeSigned = attributeSigned{}
+	AttributeEther = attributeEther{}
+	AttributeABinary = attributeABinary{}
+	AttributeComboIP = attributeComboIP{}
+}
+
+// unsigned returns value, which can be of any integer type, as a uint64. ok
+// is false if value is not an integer or is not in the range 0 to limit.
+func unsigned(value interface{}, limit uint64) (n uint64, ok bool) {
+	switch v := value.(type) {
+	case uint8:
+		n = uint64(v)
+	case uint16:
+		n = uint64(v)
+	case uint32:
+		n = uint64(v)
+	case uint64:
+		n = v
+	case uint:
+		n = uint64(v)
+	case int:
+		if v < 0 {
+			return
+		}
+		n = uint64(v)
+	case int32:
+		if v < 0 {
+			return
+		}
+		n = uint64(v)
+	case int64:
+		if v < 0 {
+			return
+		}
+		n = uint64(v)
+	default:
+		return
+	}
+	return n, n <= limit
+}
+
+// signed returns value, which can be of any integer type, as an int64. ok is
+// false if value is not an integer or is not in the range lower to upper.
+func signed(value interface{}, lower, upper int64) (n int64, ok bool) {
+	switch v := value.(type) {
+	case uint8:
+		n = int64(v)
+	case uint16:
+		n = int64(v)
+	case uint32:
+		n = int64(v)
+	case uint64:
+		if v > uint64(upper) {
+			return
+		}
+		n = int64(v)
+	case uint:
+		if uint64(v) > uint64(upper) {
+			return
+		}
+		n = int64(v)
+	case int:
+		n = int64(v)
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	default:
+		return
+	}
+	return n, n >= lower && n <= upper
+}
+
+type attributeByte struct{}
+
+func (attributeByte) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != 1 {
+		return nil, errors.New("radius: byte attribute has invalid size")
+	}
+	return value[0], nil
+}
+
+func (attributeByte) Transform(value interface{}) (interface{}, error) {
+	n, ok := unsigned(value, 0xff)
+	if !ok {
+		return nil, errors.New("radius: byte attribute must be an integer from 0 to 255")
+	}
+	return uint8(n), nil
+}
+
+func (c attributeByte) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	value, err := c.Transform(value)
+	if err != nil {
+		return nil, err
+	}
+	return []byte{value.(uint8)}, nil
+}
+
+type attributeShort struct{}
+
+func (attributeShort) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != 2 {
+		return nil, errors.New("radius: short attribute has invalid size")
+	}
+	return binary.BigEndian.Uint16(value), nil
+}
+
+func (attributeShort) Transform(value interface{}) (interface{}, error) {
+	n, ok := unsigned(value, 0xffff)
+	if !ok {
+		return nil, errors.New("radius: short attribute must be an integer from 0 to 65535")
+	}
+	return uint16(n), nil
+}
+
+func (c attributeShort) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	value, err := c.Transform(value)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, 2)
+	binary.BigEndian.PutUint16(raw, value.(uint16))
+	return raw, nil
+}
+
+type attributeSigned struct{}
+
+func (attributeSigned) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != 4 {
+		return nil, errors.New("radius: signed attribute has invalid size")
+	}
+	return int32(binary.BigEndian.Uint32(value)), nil
+}
+
+func (attributeSigned) Transform(value interface{}) (interface{}, error) {
+	n, ok := signed(value, math.MinInt32, math.MaxInt32)
+	if !ok {
+		return nil, errors.New("radius: signed attribute must be an integer from -2147483648 to 2147483647")
+	}
+	return int32(n), nil
+}
+
+func (c attributeSigned) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	value, err := c.Transform(value)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, 4)
+	binary.BigEndian.PutUint32(raw, uint32(value.(int32)))
+	return raw, nil
+}
+
+type attributeEther struct{}
+
+func (attributeEther) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != 6 {
+		return nil, errors.New("radius: ether attribute has invalid size")
+	}
+	v := make([]byte, len(value))
+	copy(v, value)
+	return net.HardwareAddr(v), nil
+}
+
+func (attributeEther) Transform(value interface{}) (interface{}, error) {
+	if str, ok := value.(string); ok {
+		return net.ParseMAC(str)
+	}
+	return value, nil
+}
+
+func (attributeEther) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	addr, ok := value.(net.HardwareAddr)
+	if !ok {
+		return nil, errors.New("radius: ether attribute must be net.HardwareAddr")
+	}
+	if len(addr) != 6 {
+		return nil, errors.New("radius: ether attribute must be a 48-bit address")
+	}
+	return []byte(addr), nil
+}
+
+// attributeABinary is a distinct type, so that abinary attributes are not
+// mistaken for string attributes when codecs are compared.
+type attributeABinary struct {
+	attributeString
+}
+
+type attributeComboIP struct{}
+
+func (attributeComboIP) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != net.IPv4len && len(value) != net.IPv6len {
+		return nil, errors.New("radius: combo-ip attribute has invalid size")
+	}
+	v := make([]byte, len(value))
+	copy(v, value)
+	return net.IP(v), nil
+}
+
+func (attributeComboIP) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	ip, ok := value.(net.IP)
+	if !ok {
+		return nil, errors.New("radius: combo-ip attribute must be net.IP")
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return []byte(ip4), nil
+	}
+	if len(ip) != net.IPv6len {
+		return nil, errors.New("radius: combo-ip attribute must be an IPv4 or IPv6 net.IP")
+	}
+	return []byte(ip), nil
+}
@@ -1,10 +1,17 @@
 package radius
 
 import (
+	"context"
+	"errors"
+	"math/rand"
 	"net"
 	"time"
 )
 
+// DynamicAuthorizationPort is the UDP port on which a NAS receives
+// Disconnect-Request and CoA-Request packets (RFC 5176 section 3).
+const DynamicAuthorizationPort = "3799"
+
 // Client is a RADIUS client that can send and receive packets to and from a
 // RADIUS server.
 type Client struct {
@@ -19,17 +26,47 @@ type Client struct {
 	DialTimeout  time.Duration
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
-}
 
-// TODO: add ability to resend packets every X seconds
+	// Retransmission of requests that are not answered, as described in RFC
+	// 5080 section 2.2.1. If InitialRetransmitTime (IRT) is zero, requests
+	// are sent once and the response is awaited for ReadTimeout.
+	//
+	// Otherwise, a request is retransmitted if it is not answered within a
+	// retransmission time, which starts at IRT and doubles for each
+	// retransmission up to MaxRetransmitTime (MRT), with a random variation
+	// of 10%. Retransmission stops after MaxRetransmitCount (MRC)
+	// retransmissions, and the exchange fails if the request is not
+	// answered within MaxRetransmitDuration (MRD). Zero MRT and MRC are not
+	// limited; a zero MRD defaults to ReadTimeout.
+	InitialRetransmitTime time.Duration
+	MaxRetransmitTime     time.Duration
+	MaxRetransmitCount    int
+	MaxRetransmitDuration time.Duration
+}
 
 // Exchange sends the packet to the given server address and waits for a
 // response. nil and an error is returned upon failure.
+//
+// Retransmissions (see Client.InitialRetransmitTime) are identical to the
+// first transmission, with the same Identifier and authenticator, except for
+// Accounting-Request packets with an Acct-Delay-Time attribute. Its value is
+// increased by the number of seconds since the first transmission, which
+// changes the packet, so such retransmissions are sent with a new Identifier
+// as RFC 5080 section 2.2.1 requires. A response to any of the
+// transmissions is accepted.
 func (c *Client) Exchange(packet *Packet, addr string) (*Packet, error) {
-	wire, err := packet.Encode()
+	return c.ExchangeContext(context.Background(), packet, addr)
+}
+
+// ExchangeContext is like Exchange, but it stops dialing, writing and waiting
+// for a response as soon as ctx is done, and returns ctx.Err(). The timeouts
+// of the Client still apply.
+func (c *Client) ExchangeContext(ctx context.Context, packet *Packet, addr string) (*Packet, error) {
+	current, err := newTransmission(packet)
 	if err != nil {
 		return nil, err
 	}
+	sent := []*transmission{current}
 
 	connNet := c.Net
 	if connNet == "" {
@@ -46,39 +83,239 @@ func (c *Client) Exchange(packet *Packet, addr string) (*Packet, error) {
 		Timeout:   dialTimeout,
 		LocalAddr: c.LocalAddr,
 	}
-	conn, err := dialer.Dial(connNet, addr)
+	conn, err := dialer.DialContext(ctx, connNet, addr)
 	if err != nil {
-		return nil, err
+		return nil, contextErr(ctx, err)
 	}
+	defer conn.Close()
+
+	// Closing the connection interrupts the pending write or read.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
 
 	writeTimeout := c.WriteTimeout
 	if writeTimeout == 0 {
 		writeTimeout = defaultTimeout
 	}
-	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
-	if _, err := conn.Write(wire); err != nil {
-		conn.Close()
-		return nil, err
+	write := func(wire []byte) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_, err := conn.Write(wire)
+		return err
+	}
+	start := time.Now()
+	if err := write(current.wire); err != nil {
+		return nil, contextErr(ctx, err)
 	}
-
-	var incoming [maxPacketSize]byte
 
 	readTimeout := c.ReadTimeout
 	if readTimeout == 0 {
 		readTimeout = defaultTimeout
 	}
-	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	deadline := start.Add(readTimeout)
+	var (
+		rt          time.Duration
+		nextRetry   time.Time
+		retransmits int
+	)
+	if c.InitialRetransmitTime > 0 {
+		if c.MaxRetransmitDuration > 0 {
+			deadline = start.Add(c.MaxRetransmitDuration)
+		}
+		rt = c.retransmitTime(0)
+		nextRetry = start.Add(rt)
+	}
 
+	var incoming [maxPacketSize]byte
 	for {
+		readDeadline := deadline
+		retry := !nextRetry.IsZero() && nextRetry.Before(deadline)
+		if retry {
+			readDeadline = nextRetry
+		}
+		conn.SetReadDeadline(readDeadline)
+
 		n, err := conn.Read(incoming[:])
 		if err != nil {
-			conn.Close()
-			return nil, err
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() && retry {
+				retransmits++
+				if c.MaxRetransmitCount > 0 && retransmits >= c.MaxRetransmitCount {
+					nextRetry = time.Time{}
+				} else {
+					rt = c.retransmitTime(rt)
+					nextRetry = time.Now().Add(rt)
+				}
+				current, err = current.retransmission(time.Since(start))
+				if err != nil {
+					return nil, err
+				}
+				if current != sent[len(sent)-1] {
+					sent = append(sent, current)
+				}
+				if err := write(current.wire); err != nil {
+					return nil, contextErr(ctx, err)
+				}
+				continue
+			}
+			return nil, contextErr(ctx, err)
 		}
-		received, err := Parse(incoming[:n], packet.Secret, packet.Dictionary)
-		if err == nil && received.IsAuthentic(packet) {
-			conn.Close()
+
+		for _, t := range sent {
+			if n < 2 || incoming[1] != t.request.Identifier {
+				continue
+			}
+			received, err := ParseResponse(incoming[:n], t.request)
+			if err != nil || !received.IsAuthentic(t.request) {
+				continue
+			}
+			if received.messageAuthenticatorAttr() != nil && !received.IsMessageAuthentic(t.request) {
+				continue
+			}
 			return received, nil
 		}
 	}
 }
+
+// contextErr returns ctx.Err() if ctx is done, as err is then likely caused
+// by it, and err otherwise.
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// retransmitTime returns the retransmission time (RT) that follows prev, as
+// described in RFC 5080 section 2.2.1. prev is zero for the first
+// transmission.
+func (c *Client) retransmitTime(prev time.Duration) time.Duration {
+	// RAND is a random number between -0.1 and 0.1
+	jitter := func(d time.Duration) time.Duration {
+		return time.Duration((rand.Float64()*0.2 - 0.1) * float64(d))
+	}
+	if prev == 0 {
+		return c.InitialRetransmitTime + jitter(c.InitialRetransmitTime)
+	}
+	rt := 2*prev + jitter(prev)
+	if c.MaxRetransmitTime > 0 && rt > c.MaxRetransmitTime {
+		rt = c.MaxRetransmitTime + jitter(c.MaxRetransmitTime)
+	}
+	return rt
+}
+
+// transmission is a request as it was sent.
+type transmission struct {
+	// request has the Identifier and authenticator that were sent
+	request *Packet
+	wire    []byte
+	// for Accounting-Request packets with an Acct-Delay-Time attribute, its
+	// value in the first and in this transmission
+	hasDelay     bool
+	initialDelay uint32
+	delay        uint32
+}
+
+func newTransmission(packet *Packet) (*transmission, error) {
+	wire, err := packet.Encode()
+	if err != nil {
+		return nil, err
+	}
+	t := &transmission{
+		request: packet,
+		wire:    wire,
+	}
+	if packet.Code.hasZeroAuthenticator() {
+		// The Request Authenticator is calculated by Encode, and the
+		// response is authenticated with it.
+		sent := *packet
+		copy(sent.Authenticator[:], wire[4:20])
+		t.request = &sent
+	}
+	if packet.Code == CodeAccountingRequest {
+		if i := acctDelayTime(packet); i >= 0 {
+			t.initialDelay, t.hasDelay = packet.Attributes[i].Value.(uint32)
+			t.delay = t.initialDelay
+		}
+	}
+	return t, nil
+}
+
+// acctDelayTime returns the index of the Acct-Delay-Time attribute of p, or
+// -1 if it does not have one.
+func acctDelayTime(p *Packet) int {
+	for i, attr := range p.Attributes {
+		if attr.Vendor == 0 && attr.Type == attrAcctDelayTime {
+			return i
+		}
+	}
+	return -1
+}
+
+// retransmission returns the transmission to send elapsed after the first
+// one. It is t, unless the Acct-Delay-Time of the request has to be updated.
+func (t *transmission) retransmission(elapsed time.Duration) (*transmission, error) {
+	delay := t.initialDelay + uint32(elapsed/time.Second)
+	if !t.hasDelay || delay == t.delay {
+		return t, nil
+	}
+
+	packet := *t.request
+	packet.Attributes = append([]*Attribute(nil), t.request.Attributes...)
+	i := acctDelayTime(&packet)
+	updated := *packet.Attributes[i]
+	updated.Value = delay
+	packet.Attributes[i] = &updated
+	packet.Identifier = t.request.Identifier + 1 + byte(rand.Intn(255))
+
+	wire, err := packet.Encode()
+	if err != nil {
+		return nil, err
+	}
+	copy(packet.Authenticator[:], wire[4:20])
+	return &transmission{
+		request:      &packet,
+		wire:         wire,
+		hasDelay:     true,
+		initialDelay: t.initialDelay,
+		delay:        delay,
+	}, nil
+}
+
+// ExchangeDynamicAuthorization sends a Disconnect-Request or CoA-Request
+// packet to a NAS and waits for its ACK or NAK, like Exchange. If addr does
+// not include a port, DynamicAuthorizationPort is used.
+func (c *Client) ExchangeDynamicAuthorization(packet *Packet, addr string) (*Packet, error) {
+	if packet.Code != CodeDisconnectRequest && packet.Code != CodeCoARequest {
+		return nil, errors.New("radius: packet must be a Disconnect-Request or CoA-Request")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DynamicAuthorizationPort)
+	}
+	return c.Exchange(packet, addr)
+}
+
+// Ping sends a Status-Server packet (RFC 5997) to the server at addr, which
+// shares the given secret, to check that it is alive, and returns its
+// response. Servers answer with an Access-Accept on authentication ports and
+// an Accounting-Response on accounting ports. An error is returned if the
+// response does not have a Message-Authenticator.
+func (c *Client) Ping(addr string, secret []byte) (*Packet, error) {
+	request := New(CodeStatusServer, secret)
+	if request == nil {
+		return nil, errors.New("radius: could not generate packet identifier")
+	}
+	response, err := c.Exchange(request, addr)
+	if err != nil {
+		return nil, err
+	}
+	if response.messageAuthenticatorAttr() == nil {
+		return nil, errors.New("radius: Status-Server response has no Message-Authenticator")
+	}
+	return response, nil
+}
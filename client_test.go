@@ -0,0 +1,156 @@
+package radius_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/runner-mei/radius"
+)
+
+// lossyServer answers the requests it receives on a loopback port, except
+// for the first drop ones. The received packets are sent to the returned
+// channel.
+func lossyServer(t *testing.T, secret []byte, drop int) (string, <-chan []byte) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	received := make(chan []byte, 16)
+	go func() {
+		for {
+			buff := make([]byte, 4096)
+			n, addr, err := conn.ReadFrom(buff)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- buff[:n]
+			if drop > 0 {
+				drop--
+				continue
+			}
+			request, err := radius.Parse(buff[:n], secret, radius.Builtin)
+			if err != nil {
+				continue
+			}
+			response := radius.Packet{
+				Code:          radius.CodeAccessAccept,
+				Identifier:    request.Identifier,
+				Authenticator: request.Authenticator,
+				Secret:        secret,
+				Dictionary:    radius.Builtin,
+			}
+			if request.Code == radius.CodeAccountingRequest {
+				response.Code = radius.CodeAccountingResponse
+			}
+			wire, _ := response.Encode()
+			conn.WriteTo(wire, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), received
+}
+
+func Test_ClientRetransmit(t *testing.T) {
+	secret := []byte("retransmit")
+	client := radius.Client{
+		ReadTimeout:           5 * time.Second,
+		InitialRetransmitTime: 100 * time.Millisecond,
+		MaxRetransmitTime:     200 * time.Millisecond,
+	}
+
+	addr, received := lossyServer(t, secret, 2)
+	request := radius.New(radius.CodeAccessRequest, secret)
+	request.Add("User-Name", "nemo")
+	if _, err := client.Exchange(request, addr); err != nil {
+		t.Fatal(err)
+	}
+	first := <-received
+	for i := 0; i < 2; i++ {
+		if retransmission := <-received; !bytes.Equal(first, retransmission) {
+			t.Fatal("expecting retransmissions to be identical")
+		}
+	}
+
+	// The exchange fails once the retransmissions are exhausted.
+	client.MaxRetransmitCount = 1
+	client.MaxRetransmitDuration = 500 * time.Millisecond
+	addr, received = lossyServer(t, secret, 100)
+	if _, err := client.Exchange(request, addr); err == nil {
+		t.Fatal("expecting the exchange to fail")
+	}
+	if n := len(received); n != 2 {
+		t.Fatalf("expecting 2 transmissions, got %d", n)
+	}
+}
+
+func Test_ClientRetransmitAcctDelayTime(t *testing.T) {
+	secret := []byte("retransmit")
+	client := radius.Client{
+		ReadTimeout:           5 * time.Second,
+		InitialRetransmitTime: 700 * time.Millisecond,
+		MaxRetransmitTime:     700 * time.Millisecond,
+	}
+
+	addr, received := lossyServer(t, secret, 2)
+	request := radius.New(radius.CodeAccountingRequest, secret)
+	request.Add("Acct-Status-Type", "Start")
+	request.Add("Acct-Delay-Time", uint32(0))
+	if _, err := client.Exchange(request, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	var transmissions []*radius.Packet
+	for i := 0; i < 3; i++ {
+		p, err := radius.Parse(<-received, secret, radius.Builtin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsAuthentic(p) {
+			t.Fatalf("expecting transmission %d to be authentic", i)
+		}
+		transmissions = append(transmissions, p)
+	}
+	last := transmissions[2]
+	if delay := last.Value("Acct-Delay-Time").(uint32); delay != 1 {
+		t.Fatalf("expecting Acct-Delay-Time 1, got %d", delay)
+	}
+	if last.Identifier == transmissions[0].Identifier {
+		t.Fatal("expecting a new Identifier when Acct-Delay-Time changes")
+	}
+	if delay := request.Value("Acct-Delay-Time").(uint32); delay != 0 {
+		t.Fatal("expecting the request not to be modified")
+	}
+}
+
+func Test_ClientExchangeContext(t *testing.T) {
+	secret := []byte("context")
+	client := radius.Client{
+		ReadTimeout: 5 * time.Second,
+	}
+	addr, _ := lossyServer(t, secret, 100)
+	request := radius.New(radius.CodeAccessRequest, secret)
+	request.Add("User-Name", "nemo")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.ExchangeContext(ctx, request, addr); err != context.DeadlineExceeded {
+		t.Fatalf("expecting context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expecting the exchange to stop with the context, took %v", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ExchangeContext(ctx, request, addr); err != context.Canceled {
+		t.Fatalf("expecting context.Canceled, got %v", err)
+	}
+}
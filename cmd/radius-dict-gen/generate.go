@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cuu/radius"
+)
+
+// generator writes the Go package for the attributes of a dictionary.
+type generator struct {
+	Package    string
+	ImportPath string
+	// Name is the name of the dictionary file, and Vendors and Attrs the
+	// vendors and attributes it registers.
+	Name    string
+	Vendors []radius.DictionaryVendor
+	Attrs   []radius.DictionaryAttribute
+
+	buf     bytes.Buffer
+	imports map[string]bool
+	// helpers used by the generated code
+	helpers map[string]bool
+}
+
+// goType describes how the values of an attribute are represented.
+type goType struct {
+	// Name is the Go type of values in the helpers. Enum is set for named
+	// integer types, which have the underlying type Name.
+	Name string
+	Enum string
+	// Import is the package that Name requires, if any.
+	Import string
+	// Convert is the helper that converts decoded values, for types whose
+	// codecs return more than one type.
+	Convert string
+}
+
+// types maps dictionary data types to their Go types.
+var types = map[string]goType{
+	"string":     {Name: "string", Convert: "toString"},
+	"octets":     {Name: "[]byte", Convert: "toBytes"},
+	"abinary":    {Name: "[]byte", Convert: "toBytes"},
+	"vsa":        {Name: "[]byte", Convert: "toBytes"},
+	"ifid":       {Name: "[]byte"},
+	"ipaddr":     {Name: "net.IP", Import: "net"},
+	"ipv6addr":   {Name: "net.IP", Import: "net"},
+	"combo-ip":   {Name: "net.IP", Import: "net"},
+	"ipv4prefix": {Name: "*net.IPNet", Import: "net"},
+	"ipv6prefix": {Name: "*net.IPNet", Import: "net"},
+	"ether":      {Name: "net.HardwareAddr", Import: "net"},
+	"integer":    {Name: "uint32"},
+	"uint32":     {Name: "uint32"},
+	"integer64":  {Name: "uint64"},
+	"uint64":     {Name: "uint64"},
+	"byte":       {Name: "uint8"},
+	"uint8":      {Name: "uint8"},
+	"short":      {Name: "uint16"},
+	"uint16":     {Name: "uint16"},
+	"signed":     {Name: "int32"},
+	"int32":      {Name: "int32"},
+	"date":       {Name: "time.Time", Import: "time"},
+	"tlv":        {Name: "[]*radius.Attribute"},
+}
+
+// typeOf returns the Go type of the values of attr, taking its flags into
+// account like the dictionary parser. ok is false if the type is not known.
+func typeOf(attr *radius.DictionaryAttribute) (t goType, ok bool) {
+	t, ok = types[attr.DataType]
+	if !ok {
+		return
+	}
+	if _, array := flagValue(attr, "array"); array {
+		t = types["octets"]
+	}
+	encrypt, _ := flagValue(attr, "encrypt")
+	switch encrypt {
+	case "1", "3":
+		t = goType{Name: "string"}
+	case "2":
+		t = types["octets"]
+	}
+	if _, hasTag := flagValue(attr, "has_tag"); hasTag && encrypt == "" && t.Name == "string" {
+		// tagged text is decoded as a string
+		t.Convert = ""
+	}
+	if len(attr.Values) > 0 {
+		switch t.Name {
+		case "uint32", "uint16", "uint8":
+			t.Enum = identifier(attr.Name)
+		}
+	}
+	return
+}
+
+// flagValue returns the value of the named flag of attr, such as "2" for encrypt=2.
+// ok is false if attr does not have the flag.
+func flagValue(attr *radius.DictionaryAttribute, name string) (value string, ok bool) {
+	for _, f := range attr.Flags {
+		if f == name {
+			return "", true
+		}
+		if strings.HasPrefix(f, name+"=") {
+			return f[len(name)+1:], true
+		}
+	}
+	return
+}
+
+// identifier returns the exported Go identifier for a dictionary name, such
+// as SessionTimeout for Session-Timeout.
+func identifier(name string) string {
+	id := camelCase(name)
+	if id == "" || !unicode.IsLetter(rune(id[0])) {
+		id = "X" + id
+	}
+	return id
+}
+
+// camelCase removes the characters of name that are not letters or digits,
+// and capitalizes the letters that follow them.
+func camelCase(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) generate() ([]byte, error) {
+	g.imports = map[string]bool{"strings": true}
+	g.helpers = make(map[string]bool)
+	names := make(map[string]string)
+
+	var body bytes.Buffer
+	for i := range g.Attrs {
+		attr := &g.Attrs[i]
+		t, ok := typeOf(attr)
+		if !ok {
+			continue
+		}
+		id := identifier(attr.Name)
+		if other, ok := names[id]; ok {
+			return nil, fmt.Errorf("attributes %s and %s have the same Go name %s", other, attr.Name, id)
+		}
+		names[id] = attr.Name
+		if t.Import != "" {
+			g.imports[t.Import] = true
+		}
+		if t.Enum != "" {
+			g.enum(attr, t)
+		}
+		g.attribute(attr, t)
+		body.Write(g.buf.Bytes())
+		g.buf.Reset()
+	}
+
+	g.printf("// Code generated by radius-dict-gen from %s. DO NOT EDIT.\n\n", g.Name)
+	g.printf("package %s\n\n", g.Package)
+	g.printf("import (\n")
+	var imports []string
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		g.printf("\t%q\n", path)
+	}
+	g.printf("\n\t%q\n)\n\n", g.ImportPath)
+
+	dict := g.dictionary()
+	text := strconv.Quote(dict)
+	if !strings.Contains(dict, "`") {
+		text = "`" + dict + "`"
+	}
+	g.printf("const dictionary = %s\n\n", text)
+	g.printf("// Register registers the attributes of %s in d. The Dictionary of the\n", g.Name)
+	g.printf("// packets passed to the functions of this package must have them registered.\n")
+	g.printf("func Register(d *radius.Dictionary) error {\n")
+	g.printf("\treturn d.LoadDictsReader(strings.NewReader(dictionary), %q)\n}\n\n", g.Name)
+	g.helperFuncs()
+	g.buf.Write(body.Bytes())
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+// dictionary returns the text of a dictionary file that registers g.Vendors
+// and g.Attrs, which is loaded by the generated Register function.
+func (g *generator) dictionary() string {
+	var b strings.Builder
+	vendors := make(map[uint32]string)
+	for _, v := range g.Vendors {
+		if _, ok := vendors[v.ID]; !ok {
+			vendors[v.ID] = v.Name
+		}
+		fmt.Fprintf(&b, "VENDOR %s %d", v.Name, v.ID)
+		if v.Format != "" {
+			fmt.Fprintf(&b, " format=%s", v.Format)
+		}
+		b.WriteString("\n")
+	}
+	var vendor string
+	for i := range g.Attrs {
+		attr := &g.Attrs[i]
+		if attr.DataType == "" {
+			continue
+		}
+		if name := vendors[attr.Vendor]; name != vendor {
+			if vendor != "" {
+				fmt.Fprintf(&b, "END-VENDOR %s\n", vendor)
+			}
+			if name != "" {
+				fmt.Fprintf(&b, "BEGIN-VENDOR %s\n", name)
+			}
+			vendor = name
+		}
+		number := strconv.Itoa(int(attr.Type))
+		if attr.Vendor == 0 && attr.Type >= 241 && attr.Type <= 246 {
+			number += "." + strconv.Itoa(int(attr.ExtendedType))
+		}
+		for _, name := range append([]string{attr.Name}, attr.Aliases...) {
+			fmt.Fprintf(&b, "ATTRIBUTE %s %s %s", name, number, attr.DataType)
+			if len(attr.Flags) > 0 {
+				fmt.Fprintf(&b, " %s", strings.Join(attr.Flags, ","))
+			}
+			b.WriteString("\n")
+		}
+		for _, v := range attr.Values {
+			fmt.Fprintf(&b, "VALUE %s %s %d\n", attr.Name, v.Name, v.Number)
+		}
+	}
+	if vendor != "" {
+		fmt.Fprintf(&b, "END-VENDOR %s\n", vendor)
+	}
+	return b.String()
+}
+
+// enum writes the named type and constants of an attribute with values.
+func (g *generator) enum(attr *radius.DictionaryAttribute, t goType) {
+	g.imports["strconv"] = true
+	g.printf("// %s is a value of the %s attribute.\n", t.Enum, attr.Name)
+	g.printf("type %s %s\n\n", t.Enum, t.Name)
+
+	g.printf("// Values of the %s attribute.\n", attr.Name)
+	g.printf("const (\n")
+	consts := make(map[string]bool)
+	var cases []radius.DictionaryValue
+	seen := make(map[uint32]bool)
+	for _, v := range attr.Values {
+		name := t.Enum + "_Value_" + camelCase(v.Name)
+		if consts[name] {
+			continue
+		}
+		consts[name] = true
+		g.printf("\t%s %s = %d\n", name, t.Enum, v.Number)
+		if !seen[v.Number] {
+			seen[v.Number] = true
+			cases = append(cases, v)
+		}
+	}
+	g.printf(")\n\n")
+
+	g.printf("// String returns the dictionary name of v, or its number.\n")
+	g.printf("func (v %s) String() string {\n", t.Enum)
+	g.printf("\tswitch v {\n")
+	for _, v := range cases {
+		g.printf("\tcase %d:\n\t\treturn %q\n", v.Number, v.Name)
+	}
+	g.printf("\t}\n")
+	g.printf("\treturn strconv.FormatUint(uint64(v), 10)\n}\n\n")
+}
+
+// attribute writes the helper functions of an attribute.
+func (g *generator) attribute(attr *radius.DictionaryAttribute, t goType) {
+	id := identifier(attr.Name)
+	typ := t.Name
+	if t.Enum != "" {
+		typ = t.Enum
+	}
+	key := fmt.Sprintf("p, %d, %d, %d", attr.Vendor, attr.Type, attr.ExtendedType)
+	_, hasTag := flagValue(attr, "has_tag")
+
+	// convert converts the interface{} v to typ, setting ok.
+	var convert string
+	switch {
+	case t.Convert != "":
+		g.helpers[t.Convert] = true
+		convert = fmt.Sprintf("value, ok := %s(v)", t.Convert)
+	case t.Enum != "":
+		convert = fmt.Sprintf("n, ok := v.(%s)\nvalue := %s(n)", t.Name, t.Enum)
+	default:
+		convert = fmt.Sprintf("value, ok := v.(%s)", t.Name)
+	}
+	// encoded is the value passed to Packet.Add.
+	encoded := "value"
+	if t.Enum != "" {
+		encoded = t.Name + "(value)"
+	}
+
+	params, results := "value "+typ, "value "+typ+", ok bool"
+	if hasTag {
+		params = "tag byte, " + params
+		results = "tag byte, " + results
+		encoded = "radius.Tagged{Tag: tag, Value: " + encoded + "}"
+	}
+
+	g.printf("// %s_Get returns the value of the first %s attribute of p.\n", id, attr.Name)
+	g.printf("func %s_Get(p *radius.Packet) (%s) {\n", id, results)
+	g.printf("\tfor _, attr := range lookup(%s) {\n", key)
+	if hasTag {
+		g.printf("\t\tif tagged, isTagged := attr.Value.(radius.Tagged); isTagged {\n")
+		g.printf("\t\t\tif value, ok = %s_value(tagged.Value); ok {\n", id)
+		g.printf("\t\t\t\treturn tagged.Tag, value, true\n\t\t\t}\n\t\t}\n")
+	} else {
+		g.printf("\t\tif value, ok = %s_value(attr.Value); ok {\n", id)
+		g.printf("\t\t\treturn\n\t\t}\n")
+	}
+	g.printf("\t}\n\treturn\n}\n\n")
+
+	if hasTag {
+		g.printf("// %s_Gets returns the tags and values of the %s attributes of p.\n", id, attr.Name)
+		g.printf("func %s_Gets(p *radius.Packet) (tags []byte, values []%s) {\n", id, typ)
+		g.printf("\tfor _, attr := range lookup(%s) {\n", key)
+		g.printf("\t\tif tagged, ok := attr.Value.(radius.Tagged); ok {\n")
+		g.printf("\t\t\tif value, ok := %s_value(tagged.Value); ok {\n", id)
+		g.printf("\t\t\t\ttags = append(tags, tagged.Tag)\n")
+		g.printf("\t\t\t\tvalues = append(values, value)\n\t\t\t}\n\t\t}\n\t}\n\treturn\n}\n\n")
+	} else {
+		g.printf("// %s_Gets returns the values of the %s attributes of p.\n", id, attr.Name)
+		g.printf("func %s_Gets(p *radius.Packet) (values []%s) {\n", id, typ)
+		g.printf("\tfor _, attr := range lookup(%s) {\n", key)
+		g.printf("\t\tif value, ok := %s_value(attr.Value); ok {\n", id)
+		g.printf("\t\t\tvalues = append(values, value)\n\t\t}\n\t}\n\treturn\n}\n\n")
+	}
+
+	g.printf("// %s_Set replaces the %s attributes of p with a single one.\n", id, attr.Name)
+	g.printf("func %s_Set(p *radius.Packet, %s) error {\n", id, params)
+	g.printf("\tattr, err := p.Dictionary.Attr(%q, %s)\n", attr.Name, encoded)
+	g.printf("\tif err != nil {\n\t\treturn err\n\t}\n")
+	g.printf("\tdel(%s)\n", key)
+	g.printf("\tp.AddAttr(attr)\n\treturn nil\n}\n\n")
+
+	g.printf("// %s_Add adds a %s attribute to p.\n", id, attr.Name)
+	g.printf("func %s_Add(p *radius.Packet, %s) error {\n", id, params)
+	g.printf("\treturn p.Add(%q, %s)\n}\n\n", attr.Name, encoded)
+
+	g.printf("// %s_Del removes the %s attributes of p.\n", id, attr.Name)
+	g.printf("func %s_Del(p *radius.Packet) {\n", id)
+	g.printf("\tdel(%s)\n}\n\n", key)
+
+	g.printf("func %s_value(v interface{}) (%s, bool) {\n", id, typ)
+	g.printf("\t%s\n\treturn value, ok\n}\n\n", convert)
+}
+
+// helperFuncs writes the unexported functions shared by the helpers.
+func (g *generator) helperFuncs() {
+	g.printf(`// lookup returns the valid attributes of p with the given vendor and type.
+func lookup(p *radius.Packet, vendor uint32, t, extendedType byte) []*radius.Attribute {
+	var attrs []*radius.Attribute
+	for _, attr := range p.Attributes {
+		if !attr.Invalid && attr.Vendor == vendor && attr.Type == t && attr.ExtendedType == extendedType {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// del removes the attributes of p with the given vendor and type.
+func del(p *radius.Packet, vendor uint32, t, extendedType byte) {
+	attrs := p.Attributes[:0]
+	for _, attr := range p.Attributes {
+		if attr.Vendor != vendor || attr.Type != t || attr.ExtendedType != extendedType {
+			attrs = append(attrs, attr)
+		}
+	}
+	p.Attributes = attrs
+}
+
+`)
+	if g.helpers["toString"] {
+		g.printf(`// toString converts text values, which are decoded as string or []byte.
+func toString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+`)
+	}
+	if g.helpers["toBytes"] {
+		g.printf(`// toBytes converts octets values, which are decoded as []byte or string.
+func toBytes(v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	}
+	return nil, false
+}
+
+`)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cuu/radius"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// load returns the generator of a dictionary from the dictionaries package.
+func load(t *testing.T, name, pkg string) *generator {
+	dict := &radius.Dictionary{}
+	if err := dict.LoadDicts(filepath.Join("..", "..", "dictionaries", name)); err != nil {
+		t.Fatal(err)
+	}
+	return &generator{
+		Package:    pkg,
+		ImportPath: radiusPath,
+		Name:       name,
+		Vendors:    dict.Vendors(),
+		Attrs:      dict.Attributes(),
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	g := load(t, "dictionary.microsoft", "microsoft")
+	src, err := g.generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "microsoft.golden")
+	if *update {
+		if err := os.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, expected) {
+		t.Fatalf("generated code does not match %s; run go test -update to update it", golden)
+	}
+
+	check(t, src)
+}
+
+// importerFunc implements gotypes.Importer with a function.
+type importerFunc func(path string) (*gotypes.Package, error)
+
+func (f importerFunc) Import(path string) (*gotypes.Package, error) {
+	return f(path)
+}
+
+// check type-checks the generated source against the radius package at the
+// root of the repository, so that it does not depend on GOPATH or modules.
+func check(t *testing.T, src []byte) {
+	fset := token.NewFileSet()
+	std := importer.ForCompiler(fset, "source", nil)
+
+	root := filepath.Join("..", "..")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(root, name), nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	conf := gotypes.Config{Importer: std}
+	radiusPkg, err := conf.Check(radiusPath, fset, files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := parser.ParseFile(fset, "generated.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Importer = importerFunc(func(path string) (*gotypes.Package, error) {
+		if path == radiusPath {
+			return radiusPkg, nil
+		}
+		return std.Import(path)
+	})
+	if _, err := conf.Check("generated", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("type-checking the generated code: %v", err)
+	}
+}
+
+func Test_GenerateDictionary(t *testing.T) {
+	for _, name := range []string{"dictionary.microsoft", "dictionary.cisco", "dictionary.3gpp"} {
+		g := load(t, name, "x")
+		// The generated dictionary registers the same attributes.
+		dict := &radius.Dictionary{}
+		if err := dict.LoadDictsReader(strings.NewReader(g.dictionary()), name); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if vendors := dict.Vendors(); !reflect.DeepEqual(vendors, g.Vendors) {
+			t.Fatalf("%s: expecting vendors %+v, got %+v", name, g.Vendors, vendors)
+		}
+		if attrs := dict.Attributes(); !reflect.DeepEqual(attrs, g.Attrs) {
+			t.Fatalf("%s: expecting attributes %+v, got %+v", name, g.Attrs, attrs)
+		}
+	}
+}
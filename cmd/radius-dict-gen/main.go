@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/cuu/radius"
+)
+
+const usage = `
+Reads a dictionary file, in the format accepted by Dictionary.LoadDicts, and
+writes a Go package with typed helpers for its attributes:
+
+  <Attr>_Get(p) (value, ok)    the value of the first attribute
+  <Attr>_Gets(p) values        the values of all of the attributes
+  <Attr>_Set(p, value) error   replaces the attributes with a single one
+  <Attr>_Add(p, value) error   adds an attribute
+  <Attr>_Del(p)                removes the attributes
+
+Integer attributes with VALUE lines get a named type with a constant for each
+value. The generated Register function registers the dictionary in a
+radius.Dictionary, which must be the Dictionary of the packets passed to the
+helpers.
+`
+
+// radiusPath is the import path of the radius package that loads the
+// dictionary, which the generated code imports by default.
+var radiusPath = reflect.TypeOf((*radius.Dictionary)(nil)).Elem().PkgPath()
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] <dictionary>\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(os.Stderr, usage)
+	}
+	pkg := flag.String("package", "", "name of the generated package (default: the name of the output directory)")
+	output := flag.String("o", "", "output file (default: stdout)")
+	importPath := flag.String("import", radiusPath, "import path of the radius package")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *pkg == "" {
+		if *output == "" {
+			fmt.Fprintln(os.Stderr, "-package is required when writing to stdout")
+			os.Exit(1)
+		}
+		abs, err := filepath.Abs(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		*pkg = strings.Replace(filepath.Base(filepath.Dir(abs)), "-", "_", -1)
+	}
+
+	dict := &radius.Dictionary{}
+	if err := dict.LoadDicts(flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	g := generator{
+		Package:    *pkg,
+		ImportPath: *importPath,
+		Name:       filepath.Base(flag.Arg(0)),
+		Vendors:    dict.Vendors(),
+		Attrs:      dict.Attributes(),
+	}
+	src, err := g.generate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -18,7 +18,7 @@ var arguments []string
 
 func acct_handler(w radius.ResponseWriter, p *radius.Packet) {
 	for _, attr := range p.Attributes {
-		name, ok := p.Dictionary.Name(attr.Type)
+		name, ok := p.Dictionary.AttrName(attr)
 		if !ok{
 			continue
 		}
@@ -59,7 +59,7 @@ func main() {
 	acct_server := radius.Server{
 		Handler:	 radius.HandlerFunc(acct_handler),
 		Secret:		[]byte(*secret),
-		Dictionary: radius.Builtin,
+		Dictionary: radius.Builtin.Snapshot(),
 		Addr:		":1813",
 	}
 	if err := acct_server.ListenAndServe(); err != nil{
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"log"
@@ -13,6 +14,7 @@ import (
 )
 
 var secret = flag.String("secret", "testing123", "shared RADIUS secret between clients and server")
+var users = flag.String("users", "", "file of \"username password\" lines used to verify CHAP requests")
 var command string
 var arguments []string
 
@@ -20,11 +22,43 @@ func acct_handler(w radius.ResponseWriter, p *radius.Packet) {
 	
 }
 
+// lookupPassword returns the cleartext password of username from the users
+// file.
+func lookupPassword(username string) (string, bool) {
+	if *users == "" {
+		return "", false
+	}
+	file, err := os.Open(*users)
+	if err != nil {
+		log.Println(err)
+		return "", false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == username {
+			return fields[1], true
+		}
+	}
+	return "", false
+}
+
 func handler(w radius.ResponseWriter, p *radius.Packet) {
 	username, password, ok := p.PAP()
 	if !ok {
-		w.AccessReject()
-		return
+		username, _, _, _, ok = p.CHAP()
+		if !ok {
+			w.AccessReject()
+			return
+		}
+		password, ok = lookupPassword(username)
+		if !ok || !p.VerifyCHAP(password) {
+			log.Printf("%s CHAP rejected (%s #%d)\n", username, w.RemoteAddr(), p.Identifier)
+			w.AccessReject()
+			return
+		}
 	}
 	log.Printf("%s with %s requesting access (%s #%d)\n", username,password, w.RemoteAddr(), p.Identifier)
 
@@ -32,7 +66,7 @@ func handler(w radius.ResponseWriter, p *radius.Packet) {
 
 	cmd.Env = os.Environ()
 	for _, attr := range p.Attributes {
-		name, ok := p.Dictionary.Name(attr.Type)
+		name, ok := p.Dictionary.AttrName(attr)
 		if !ok {
 			continue
 		}
@@ -82,6 +116,8 @@ or
  ./auth -secret testing123 ./simple-auth
 ./auth -secret testing123 ./simple-auth 888 888  
 in simple-auth, I preset the username and password 
+CHAP requests need the cleartext password, which is read from -users:
+ ./auth -secret testing123 -users users.txt ./simple-auth 888 888
 
 把得到的用户名和密码,变成os.Env中的RADIUS-USERNAMExxxxx 
 然后simple-auth 被os.exec,之后对比环境变量,是否是$1 和$2 所指定的用户名密码
@@ -109,7 +145,7 @@ func main() {
 	server := radius.Server{
 		Handler:    radius.HandlerFunc(handler),
 		Secret:     []byte(*secret),
-		Dictionary: radius.Builtin,
+		Dictionary: radius.Builtin.Snapshot(),
 	}
 
 	if err := server.ListenAndServe(); err != nil {
@@ -121,7 +157,7 @@ func main() {
 	acct_server := radius.Server{
 		Handler:	 radius.HandlerFunc(acct_handler),
 		Secret:		[]byte(*secret),
-		Dictionary: radius.Builtin,
+		Dictionary: radius.Builtin.Snapshot(),
 		Addr:		":1813",
 	}
 	if err := acct_server.ListenAndServe(); err != nil{
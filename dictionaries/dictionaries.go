@@ -0,0 +1,29 @@
+// Package dictionaries bundles common vendor dictionaries, in the format used
+// by FreeRADIUS, for programs that cannot load them from disk. The bundle
+// contains the vendor-specific attributes of 3GPP, Cisco, Huawei, Juniper,
+// Microsoft, MikroTik and the Wi-Fi Alliance (WISPr).
+//
+// The bundle is merged into a dictionary with Load:
+//
+//	if err := dictionaries.Load(radius.Builtin); err != nil {
+//	  log.Fatal(err)
+//	}
+package dictionaries
+
+import (
+	"embed"
+
+	"github.com/runner-mei/radius"
+)
+
+// FS holds the bundled dictionary files. The file named "dictionary"
+// includes all of the others.
+//
+//go:embed dictionary dictionary.*
+var FS embed.FS
+
+// Load loads all of the bundled dictionaries into d. Attributes which are
+// already registered in d keep their codec.
+func Load(d *radius.Dictionary) error {
+	return d.LoadDictsFS(FS, "dictionary")
+}
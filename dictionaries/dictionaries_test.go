@@ -0,0 +1,48 @@
+package dictionaries_test
+
+import (
+	"testing"
+
+	"github.com/runner-mei/radius"
+	"github.com/runner-mei/radius/dictionaries"
+)
+
+func Test_Load(t *testing.T) {
+	// The bundle is loaded into a dictionary of its own, so that Builtin,
+	// which other tests use, is left unchanged.
+	dict := &radius.Dictionary{}
+	if err := dictionaries.Load(dict); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("secret")
+	p := radius.New(radius.CodeAccessAccept, secret)
+	p.Dictionary = dict
+	for name, value := range map[string]interface{}{
+		"Cisco-AVPair":               "shell:priv-lvl=15",
+		"Mikrotik-Wireless-Enc-Algo": "AES-CCM",
+		"MS-MPPE-Encryption-Policy":  "Encryption-Required",
+	} {
+		if err := p.Add(name, value); err != nil {
+			t.Fatalf("adding %s: %v", name, err)
+		}
+	}
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := radius.Parse(b, secret, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr := q.Attr("Cisco-AVPair"); attr == nil || attr.Vendor != 9 {
+		t.Fatal("expecting Cisco-AVPair from vendor 9")
+	}
+	if s := q.String("Mikrotik-Wireless-Enc-Algo"); s != "AES-CCM" {
+		t.Fatalf("expecting Mikrotik-Wireless-Enc-Algo AES-CCM, got %q", s)
+	}
+	if s := q.String("MS-MPPE-Encryption-Policy"); s != "Encryption-Required" {
+		t.Fatalf("expecting MS-MPPE-Encryption-Policy Encryption-Required, got %q", s)
+	}
+}
@@ -1,258 +1,612 @@
 package radius
 
 import (
-	"bufio"
 	"errors"
-	"fmt"
-	"log"
-	"os"
-	"sync"
-	//	"io"
-	"bytes"
-	"encoding/binary"
-	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 )
 
 var builtinOnce sync.Once
 
+// defaultVendor is the name under which the standard attribute space is
+// registered.
+const defaultVendor = "default"
+
 // Builtin is the built-in dictionary. It is initially loaded with the
 // attributes defined in RFC 2865 and RFC 2866.
 var Builtin *Dictionary
 
 func initDictionary() {
 	Builtin = &Dictionary{}
-	Builtin.RegisterVendor("default", 1)
 }
 
 type dictEntry struct {
-	Type  byte
-	Name  string
-	Codec AttributeCodec
+	Vendor       uint32
+	Type         byte
+	ExtendedType byte
+	Name         string
+	Codec        AttributeCodec
+	// Concat is set for attributes whose values may span multiple
+	// attributes (the dictionary concat flag).
+	Concat bool
+	// data type and flags of the dictionary ATTRIBUTE line the entry was
+	// loaded from, if any
+	DataType string
+	Flags    []string
+}
+
+// attrKey identifies an attribute within an attribute space. ExtendedType is
+// only set for attributes in the RFC 6929 extended attribute space.
+type attrKey struct {
+	Type         byte
+	ExtendedType byte
 }
 
 type dictAttr struct {
-	attributesByType [1069]*dictEntry
+	attributesByType map[attrKey]*dictEntry
 	attributesByName map[string]*dictEntry
 }
 
 // Dictionary stores mappings between attribute names and types and
 // AttributeCodecs.
+//
+// A Dictionary is safe for concurrent use. Attributes of a vendor are
+// registered and looked up through the VendorDictionary returned by Vendor,
+// without changing the Dictionary itself.
 type Dictionary struct {
 	mu       sync.RWMutex
-	Vendor   string
 	VendorId map[string]int // initied to zero,so vendor must above zero
 	values   map[string]*dictAttr
+	formats  map[string]vendorFormat
+	// vendor names by ID
+	vendorNames map[uint32]string
+	// vendor names sorted by ID, and by name for the same ID; the order in
+	// which names are looked up
+	vendorOrder []string
+	// vendor selected with the deprecated SwitchVendor, "" for the standard
+	// attribute space
+	switched string
+	// data types registered with RegisterDataType
+	types map[string]AttributeCodec
+	// set for snapshots, which are read-only and accessed without locking
+	frozen bool
+}
+
+// errReadOnly is returned when registering attributes in a snapshot.
+var errReadOnly = errors.New("radius: dictionary is read-only")
+
+func (d *Dictionary) rlock() {
+	if !d.frozen {
+		d.mu.RLock()
+	}
 }
 
-func (d *Dictionary) to_byte(bst string) byte {
-	i, _ := strconv.ParseInt(bst, 10, 8)
-	//fmt.Println(i)
-	b_buf := bytes.NewBuffer([]byte{})
-	// intel x86 is littleEndian
-	binary.Write(b_buf, binary.LittleEndian, i)
-	return b_buf.Bytes()[0]
+func (d *Dictionary) runlock() {
+	if !d.frozen {
+		d.mu.RUnlock()
+	}
 }
 
+// ParseAttrs registers the attribute of a dictionary ATTRIBUTE line, split
+// into fields. false is returned if arr is not a valid ATTRIBUTE line.
+// Vendor attributes must name their vendor in the fifth field, unless the
+// line follows a deprecated ParseBeginVendor.
 func (d *Dictionary) ParseAttrs(arr []string) bool {
-	if len(arr) != 4 {
+	if len(arr) == 0 || strings.ToUpper(arr[0]) != "ATTRIBUTE" {
 		return false
 	}
-	if strings.ToUpper(arr[0]) == "ATTRIBUTE" {
-		num, _ := strconv.ParseInt(arr[2], 10, 32)
-		if num > 255 {
-			return false
-		}
-		//		fmt.Println(arr)
-		switch arr[3] {
-		case "string":
-			d.MustRegister(arr[1], d.to_byte(arr[2]), AttributeString)
-		case "integer":
-			d.MustRegister(arr[1], d.to_byte(arr[2]), AttributeInteger)
-		case "ipaddr":
-			d.MustRegister(arr[1], d.to_byte(arr[2]), AttributeAddress)
-		case "octets":
-			d.MustRegister(arr[1], d.to_byte(arr[2]), AttributeString)
-		case "date":
-			d.MustRegister(arr[1], d.to_byte(arr[2]), AttributeTime)
-		}
-		return true
-	}
-
-	return false
+	p := &dictParser{d: d, vendor: d.switchedVendor()}
+	return p.parseAttribute(arr) == nil
 }
 
+// ParseVendor registers the vendor of a dictionary VENDOR line, split into
+// fields. false is returned if arr is not a valid VENDOR line.
 func (d *Dictionary) ParseVendor(arr []string) bool {
-	if len(arr) != 3 {
+	if len(arr) == 0 || strings.ToUpper(arr[0]) != "VENDOR" {
 		return false
 	}
+	p := &dictParser{d: d}
+	return p.parseVendor(arr) == nil
+}
 
-	if strings.ToUpper(arr[0]) == "VENDOR" {
-		vid, _ := strconv.Atoi(arr[2])
-		if vid <= 0 {
-			panic("ParseVendor ID <= 0 ")
-			return false
-		}
-		d.RegisterVendor(arr[1], vid)
-		return true
+// ParseValue registers the value name of a dictionary VALUE line, split into
+// fields. false is returned if arr is not a valid VALUE line.
+func (d *Dictionary) ParseValue(arr []string) bool {
+	if len(arr) == 0 || strings.ToUpper(arr[0]) != "VALUE" {
+		return false
 	}
-
-	return false
+	p := &dictParser{d: d}
+	return p.parseValue(arr) == nil
 }
 
+// ParseBeginVendor selects the vendor of a dictionary BEGIN-VENDOR line,
+// split into fields, with SwitchVendor. false is returned if arr is not a
+// BEGIN-VENDOR line.
+//
+// Deprecated: use LoadDictsReader to load dictionary text, or name the vendor
+// in the fifth field of the lines passed to ParseAttrs.
 func (d *Dictionary) ParseBeginVendor(arr []string) bool {
-	if len(arr) != 2 {
+	if len(arr) != 2 || strings.ToUpper(arr[0]) != "BEGIN-VENDOR" {
 		return false
 	}
-
-	if strings.ToUpper(arr[0]) == "BEGIN-VENDOR" {
-		d.SwitchVendor(arr[1])
-		return true
-	}
-
-	return false
-
+	d.SwitchVendor(arr[1])
+	return true
 }
 
+// ParseEndVendor selects the standard attribute space again after a
+// dictionary END-VENDOR line, split into fields. false is returned if arr is
+// not an END-VENDOR line.
+//
+// Deprecated: use LoadDictsReader to load dictionary text, or name the vendor
+// in the fifth field of the lines passed to ParseAttrs.
 func (d *Dictionary) ParseEndVendor(arr []string) bool {
-	if len(arr) != 2 {
+	if len(arr) != 2 || strings.ToUpper(arr[0]) != "END-VENDOR" {
 		return false
 	}
-	if strings.ToUpper(arr[0]) == "END-VENDOR" {
-
-		d.SwitchVendor("default")
-		return true
-	}
-
-	return false
+	d.SwitchVendor(defaultVendor)
+	return true
 }
 
-func (d *Dictionary) LoadDicts(path string) {
-	dir, err := filepath.Abs(filepath.Dir(path))
-	if err == nil {
-		fmt.Println(dir)
-	}
-
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		fmt.Println(path, " not existed")
+// SwitchVendor selects the registered vendor v for ParseAttrs and Values.
+// Nothing else is affected by it: every other method either takes the
+// vendor explicitly, or uses the standard attribute space, which is also
+// selected by "default".
+//
+// Deprecated: use Vendor(v), which returns the attribute space of a vendor
+// without changing the Dictionary.
+func (d *Dictionary) SwitchVendor(v string) {
+	if d.frozen {
 		return
 	}
-
-	inFile, _ := os.Open(path)
-	defer inFile.Close()
-	scanner := bufio.NewScanner(inFile)
-	//	scanner.Split(bufio.ScanLines)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		arr := strings.Fields(line)
-
-		if d.ParseAttrs(arr) {
-			continue
-		}
-		if d.ParseVendor(arr) {
-			continue
-		}
-		if d.ParseBeginVendor(arr) {
-			continue
-		}
-		if d.ParseEndVendor(arr) {
-			continue
-		}
-
-		log.Println("[DICT] parse line fail in ", path, "-", line)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case v == defaultVendor:
+		d.switched = ""
+	case d.VendorId[v] != 0:
+		d.switched = v
 	}
 }
 
+// switchedVendor returns the vendor selected with SwitchVendor.
+func (d *Dictionary) switchedVendor() string {
+	d.rlock()
+	defer d.runlock()
+	return d.switched
+}
+
+// Values returns the attributes of the vendor selected with SwitchVendor.
+//
+// Deprecated: use Vendor(v) to look up the attributes of a vendor.
 func (d *Dictionary) Values() *dictAttr {
-	return d.values[d.Vendor]
+	d.rlock()
+	defer d.runlock()
+	return d.Vendor(d.switched).values()
 }
 
+// GetVendorId returns the ID of the named vendor, or zero if the vendor is not
+// registered.
 func (d *Dictionary) GetVendorId(v string) int {
+	d.rlock()
+	defer d.runlock()
 	return d.VendorId[v]
 }
 
-func (d *Dictionary) SwitchVendor(v string) {
-	if d.VendorId[v] != 0 {
-		d.Vendor = v
-	}
-}
-
+// RegisterVendor registers a vendor, whose attributes can then be registered
+// with d.Vendor(v).Register. It panics if id is not positive or if d is a
+// snapshot.
 func (d *Dictionary) RegisterVendor(v string, id int) {
 	if id <= 0 {
 		panic("RegisterVendor ID must > 0")
-		return
 	}
+	if d.frozen {
+		panic(errReadOnly)
+	}
+	d.addVendor(v, id, defaultVendorFormat)
+}
+
+// addVendor registers a vendor and the format of its Vendor-Specific
+// attributes. false is returned if a vendor with the name was already
+// registered.
+func (d *Dictionary) addVendor(v string, id int, format vendorFormat) bool {
+	if d.frozen {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if d.VendorId == nil {
 		d.VendorId = make(map[string]int)
 	}
+	if d.VendorId[v] != 0 {
+		return false
+	}
+	d.VendorId[v] = id
+	if v != defaultVendor {
+		if d.vendorNames == nil {
+			d.vendorNames = make(map[uint32]string)
+		}
+		if _, ok := d.vendorNames[uint32(id)]; !ok {
+			d.vendorNames[uint32(id)] = v
+		}
+		i := sort.Search(len(d.vendorOrder), func(i int) bool {
+			other := d.vendorOrder[i]
+			return d.VendorId[other] > id || (d.VendorId[other] == id && other > v)
+		})
+		d.vendorOrder = append(d.vendorOrder, "")
+		copy(d.vendorOrder[i+1:], d.vendorOrder[i:])
+		d.vendorOrder[i] = v
+	}
+	if d.values == nil {
+		d.values = make(map[string]*dictAttr)
+	}
+	if d.values[v] == nil {
+		d.values[v] = &dictAttr{}
+	}
+	if format != defaultVendorFormat {
+		if d.formats == nil {
+			d.formats = make(map[string]vendorFormat)
+		}
+		d.formats[v] = format
+	}
+	return true
+}
+
+// vendorFormat returns the format of the Vendor-Specific attributes of the
+// given vendor ID.
+func (d *Dictionary) vendorFormat(vendor uint32) vendorFormat {
+	d.rlock()
+	defer d.runlock()
+	if format, ok := d.formats[d.vendorNames[vendor]]; ok {
+		return format
+	}
+	return defaultVendorFormat
+}
 
-	if d.VendorId[v] == 0 {
-		d.Vendor = v
-		d.VendorId[v] = id
-		if d.values == nil {
-			d.values = make(map[string]*dictAttr)
+// Snapshot returns a read-only copy of d. Lookups in the snapshot do not take
+// any locks, which makes it suited for sharing between the goroutines of a
+// Server. Registering attributes or vendors in the snapshot fails, and changes
+// made to d afterwards are not visible in it. Calling Snapshot on a snapshot
+// returns the snapshot itself.
+func (d *Dictionary) Snapshot() *Dictionary {
+	if d.frozen {
+		return d
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	s := &Dictionary{
+		VendorId:    make(map[string]int, len(d.VendorId)),
+		values:      make(map[string]*dictAttr, len(d.values)),
+		formats:     make(map[string]vendorFormat, len(d.formats)),
+		vendorNames: make(map[uint32]string, len(d.vendorNames)),
+		vendorOrder: append([]string(nil), d.vendorOrder...),
+		types:       make(map[string]AttributeCodec, len(d.types)),
+		switched:    d.switched,
+		frozen:      true,
+	}
+	for name, codec := range d.types {
+		s.types[name] = codec
+	}
+	for name, id := range d.VendorId {
+		s.VendorId[name] = id
+	}
+	for name, format := range d.formats {
+		s.formats[name] = format
+	}
+	for id, name := range d.vendorNames {
+		s.vendorNames[id] = name
+	}
+	// Entries are never modified once registered, so they can be shared.
+	for name, values := range d.values {
+		copied := &dictAttr{
+			attributesByType: make(map[attrKey]*dictEntry, len(values.attributesByType)),
+			attributesByName: make(map[string]*dictEntry, len(values.attributesByName)),
+		}
+		for key, entry := range values.attributesByType {
+			copied.attributesByType[key] = entry
 		}
-		if d.values[d.Vendor] == nil {
-			d.values[d.Vendor] = &dictAttr{}
+		for key, entry := range values.attributesByName {
+			copied.attributesByName[key] = entry
 		}
+		s.values[name] = copied
 	}
+	return s
+}
+
+// RegisterDataType registers the AttributeCodec used for attributes of the
+// named data type when loading dictionary files, such as a custom codec for a
+// vendor's data type. It replaces the codec of a built-in data type with the
+// same name.
+func (d *Dictionary) RegisterDataType(name string, codec AttributeCodec) error {
+	if codec == nil {
+		return errors.New("radius: nil AttributeCodec")
+	}
+	if d.frozen {
+		return errReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.types == nil {
+		d.types = make(map[string]AttributeCodec)
+	}
+	d.types[name] = codec
+	return nil
 }
 
-// Register registers the AttributeCodec for the given attribute name and type.
+// Register registers the AttributeCodec for the given attribute name and type
+// in the standard attribute space.
 func (d *Dictionary) Register(name string, t byte, codec AttributeCodec) error {
+	return d.Vendor("").Register(name, t, codec)
+}
+
+// RegisterExtended registers the AttributeCodec for the given attribute name
+// and RFC 6929 extended type. t must be one of 241 to 246.
+func (d *Dictionary) RegisterExtended(name string, t, extendedType byte, codec AttributeCodec) error {
+	if !isExtendedType(t) {
+		return errors.New("radius: invalid extended attribute type")
+	}
+	return d.registerIn("", &dictEntry{
+		Type:         t,
+		ExtendedType: extendedType,
+		Name:         name,
+		Codec:        codec,
+	})
+}
+
+// errAttributeRegistered is returned by registerIn if an attribute with the
+// same type is already registered.
+var errAttributeRegistered = errors.New("radius: attribute already registered")
+
+// registerIn registers entry in the attribute space of the given vendor name,
+// or in the standard attribute space if vendor is empty. entry.Vendor is set
+// by registerIn.
+func (d *Dictionary) registerIn(vendor string, entry *dictEntry) error {
+	if d.frozen {
+		return errReadOnly
+	}
 	d.mu.Lock()
+	defer d.mu.Unlock()
+	if vendor == "" {
+		vendor = defaultVendor
+	}
+	if vendor != defaultVendor && d.VendorId[vendor] == 0 {
+		return errors.New("radius: vendor not registered")
+	}
 	if d.values == nil {
 		d.values = make(map[string]*dictAttr)
 	}
-	if d.values[d.Vendor] == nil {
-		d.values[d.Vendor] = &dictAttr{}
+	if d.values[vendor] == nil {
+		d.values[vendor] = &dictAttr{}
 	}
-	if d.VendorId == nil {
-		d.VendorId = make(map[string]int)
+	values := d.values[vendor]
+	key := attrKey{Type: entry.Type, ExtendedType: entry.ExtendedType}
+	if values.attributesByType[key] != nil {
+		return errAttributeRegistered
 	}
-	if d.Values().attributesByType[t] != nil {
-		d.mu.Unlock()
-		return errors.New("radius: attribute already registered")
+	if vendor != defaultVendor {
+		entry.Vendor = uint32(d.VendorId[vendor])
 	}
-	entry := &dictEntry{
-		Type:  t,
-		Name:  name,
-		Codec: codec,
+	if values.attributesByType == nil {
+		values.attributesByType = make(map[attrKey]*dictEntry)
 	}
-	d.Values().attributesByType[t] = entry
-	if d.Values().attributesByName == nil {
-		d.Values().attributesByName = make(map[string]*dictEntry)
+	values.attributesByType[key] = entry
+	if values.attributesByName == nil {
+		values.attributesByName = make(map[string]*dictEntry)
 	}
+	values.attributesByName[entry.Name] = entry
+	return nil
+}
 
-	d.Values().attributesByName[name] = entry
-	d.mu.Unlock()
+// registerAlias registers name as an additional name of the attribute with
+// the given type in the attribute space of the given vendor name.
+func (d *Dictionary) registerAlias(vendor, name string, key attrKey) error {
+	if d.frozen {
+		return errReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if vendor == "" {
+		vendor = defaultVendor
+	}
+	values := d.values[vendor]
+	if values == nil || values.attributesByType[key] == nil {
+		return errors.New("radius: attribute not registered")
+	}
+	entry := values.attributesByType[key]
+	if existing := values.attributesByName[name]; existing != nil {
+		if existing != entry {
+			return errors.New("radius: attribute name already registered")
+		}
+		return nil
+	}
+	values.attributesByName[name] = entry
 	return nil
 }
 
+// MustRegisterExtended is a helper for RegisterExtended that panics if it
+// returns an error.
+func (d *Dictionary) MustRegisterExtended(name string, t, extendedType byte, codec AttributeCodec) {
+	if err := d.RegisterExtended(name, t, extendedType, codec); err != nil {
+		panic(err)
+	}
+}
+
 // MustRegister is a helper for Register that panics if it returns an error.
 func (d *Dictionary) MustRegister(name string, t byte, codec AttributeCodec) {
-	if err := d.Register(name, t, codec); err != nil {
+	d.Vendor("").MustRegister(name, t, codec)
+}
+
+// errNotEnumerable is returned by RegisterValue for attributes whose codec
+// does not support named values.
+var errNotEnumerable = errors.New("radius: named values require an integer attribute")
+
+// RegisterValue registers a name for the given value of an enumerated
+// attribute, like a dictionary VALUE line does. The attribute must already be
+// registered with the AttributeInteger, AttributeTaggedInteger, AttributeByte
+// or AttributeShort codec, which is then wrapped in a codec that accepts value
+// names in addition to numbers and converts values to their names when
+// printed.
+func (d *Dictionary) RegisterValue(attrName, valueName string, value uint32) error {
+	if d.frozen {
+		return errReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry := d.getLocked(attrName)
+	if entry == nil {
+		return errors.New("radius: attribute name not registered")
+	}
+
+	enum, ok := entry.Codec.(*attributeEnum)
+	if !ok {
+		switch entry.Codec {
+		case AttributeInteger, AttributeTaggedInteger, AttributeByte, AttributeShort:
+		default:
+			return errNotEnumerable
+		}
+		enum = &attributeEnum{
+			AttributeCodec: entry.Codec,
+		}
+	}
+
+	updated := *entry
+	updated.Codec = enum.with(valueName, value)
+	d.replaceLocked(entry, &updated)
+	return nil
+}
+
+// SetConcat sets whether values of the named attribute that are longer than
+// a single attribute are split across multiple attributes when encoding, and
+// consecutive attributes of the type are joined when parsing, like the
+// dictionary concat flag. It is set for EAP-Message in the Builtin
+// dictionary. The attribute must be registered with a codec whose values
+// can be split at any byte, such as AttributeString.
+func (d *Dictionary) SetConcat(attrName string, concat bool) error {
+	if d.frozen {
+		return errReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry := d.getLocked(attrName)
+	if entry == nil {
+		return errors.New("radius: attribute name not registered")
+	}
+	if isLongExtendedType(entry.Type) {
+		return errors.New("radius: long extended attributes are always split")
+	}
+	updated := *entry
+	updated.Concat = concat
+	d.replaceLocked(entry, &updated)
+	return nil
+}
+
+// replaceLocked replaces a registered entry, under all of its names. Entries
+// are replaced instead of modified, so that entries which are in use never
+// change. d.mu must be held.
+func (d *Dictionary) replaceLocked(entry, updated *dictEntry) {
+	values := d.vendorValues(entry.Vendor)
+	values.attributesByType[attrKey{Type: entry.Type, ExtendedType: entry.ExtendedType}] = updated
+	for name, e := range values.attributesByName {
+		if e == entry {
+			values.attributesByName[name] = updated
+		}
+	}
+}
+
+// MustRegisterValue is a helper for RegisterValue that panics if it returns
+// an error.
+func (d *Dictionary) MustRegisterValue(attrName, valueName string, value uint32) {
+	if err := d.RegisterValue(attrName, valueName, value); err != nil {
 		panic(err)
 	}
 }
 
-func (d *Dictionary) get(name string) (t byte, codec AttributeCodec, ok bool) {
-	d.mu.RLock()
-	entry := d.Values().attributesByName[name]
-	d.mu.RUnlock()
+// vendorValues returns the attributes registered for the given vendor ID.
+// Zero refers to the standard attribute space. d.mu must be held.
+func (d *Dictionary) vendorValues(vendor uint32) *dictAttr {
+	if vendor == 0 {
+		return d.values[defaultVendor]
+	}
+	name, ok := d.vendorNames[vendor]
+	if !ok {
+		return nil
+	}
+	return d.values[name]
+}
+
+// lookup returns the entry registered for the given attribute, or nil if
+// there is none.
+func (d *Dictionary) lookup(attr *Attribute) *dictEntry {
+	d.rlock()
+	defer d.runlock()
+	values := d.vendorValues(attr.Vendor)
+	if values == nil {
+		return nil
+	}
+	return values.attributesByType[attrKey{Type: attr.Type, ExtendedType: attr.ExtendedType}]
+}
+
+// hasVendor returns if attributes have been registered for the given vendor
+// ID.
+func (d *Dictionary) hasVendor(vendor uint32) bool {
+	d.rlock()
+	defer d.runlock()
+	return d.vendorValues(vendor) != nil
+}
+
+// get returns the entry registered under the given name. Names are looked up
+// in the standard attribute space first, followed by the vendors in the order
+// of their IDs, so that a name defined by several vendors always refers to
+// the attribute of the same vendor.
+func (d *Dictionary) get(name string) *dictEntry {
+	d.rlock()
+	defer d.runlock()
+	return d.getLocked(name)
+}
+
+// getLocked implements get. d.mu must be held.
+func (d *Dictionary) getLocked(name string) *dictEntry {
+	if values := d.vendorValues(0); values != nil {
+		if entry := values.attributesByName[name]; entry != nil {
+			return entry
+		}
+	}
+	for _, vendor := range d.vendorOrder {
+		if values := d.values[vendor]; values != nil {
+			if entry := values.attributesByName[name]; entry != nil {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// codec returns the AttributeCodec for the given attribute, taking its vendor
+// into account. AttributeUnknown is returned if the attribute is not
+// registered.
+func (d *Dictionary) codec(attr *Attribute) AttributeCodec {
+	if entry := d.lookup(attr); entry != nil {
+		return entry.Codec
+	}
+	return AttributeUnknown
+}
+
+// AttrName returns the registered name for the given attribute, taking its
+// vendor into account. ok is false if the attribute is not registered.
+func (d *Dictionary) AttrName(attr *Attribute) (name string, ok bool) {
+	entry := d.lookup(attr)
 	if entry == nil {
 		return
 	}
-	t = entry.Type
-	codec = entry.Codec
+	name = entry.Name
 	ok = true
 	return
 }
 
 // Attr returns a new *Attribute whose type is registered under the given
-// name.
+// name. Names are looked up in the standard attribute space first, followed
+// by the vendors in the order of their IDs; use d.Vendor(v).Attr to look up a
+// name of a specific vendor.
 //
 // If name is not registered, nil and an error is returned.
 //
@@ -260,11 +614,16 @@ func (d *Dictionary) get(name string) (t byte, codec AttributeCodec, ok bool) {
 // first transformed before being stored in *Attribute. If the transform
 // function returns an error, nil and the error is returned.
 func (d *Dictionary) Attr(name string, value interface{}) (*Attribute, error) {
-	t, codec, ok := d.get(name)
-	if !ok {
+	return newAttribute(d.get(name), value)
+}
+
+// newAttribute returns a new *Attribute of the given entry, as described by
+// Dictionary.Attr.
+func newAttribute(entry *dictEntry, value interface{}) (*Attribute, error) {
+	if entry == nil {
 		return nil, errors.New("radius: attribute name not registered")
 	}
-	if transformer, ok := codec.(AttributeTransformer); ok {
+	if transformer, ok := entry.Codec.(AttributeTransformer); ok {
 		transformed, err := transformer.Transform(value)
 		if err != nil {
 			return nil, err
@@ -272,8 +631,10 @@ func (d *Dictionary) Attr(name string, value interface{}) (*Attribute, error) {
 		value = transformed
 	}
 	return &Attribute{
-		Type:  t,
-		Value: value,
+		Vendor:       entry.Vendor,
+		Type:         entry.Type,
+		ExtendedType: entry.ExtendedType,
+		Value:        value,
 	}, nil
 }
 
@@ -286,12 +647,115 @@ func (d *Dictionary) MustAttr(name string, value interface{}) *Attribute {
 	return attr
 }
 
-// Name returns the registered name for the given attribute type. ok is false
-// if the given type is not registered.
+// Name returns the registered name for the given attribute type in the
+// standard attribute space. ok is false if the given type is not registered.
 func (d *Dictionary) Name(t byte) (name string, ok bool) {
-	d.mu.RLock()
-	entry := d.Values().attributesByType[t]
-	d.mu.RUnlock()
+	return d.Vendor("").Name(t)
+}
+
+// Type returns the registered type for the given attribute name in the
+// standard attribute space. ok is false if the given name is not registered.
+func (d *Dictionary) Type(name string) (t byte, ok bool) {
+	return d.Vendor("").Type(name)
+}
+
+// Codec returns the AttributeCodec for the given registered type in the
+// standard attribute space. AttributeUnknown is returned if the given type is
+// not registered.
+func (d *Dictionary) Codec(t byte) AttributeCodec {
+	return d.Vendor("").Codec(t)
+}
+
+// VendorDictionary is the attribute space of a single vendor of a Dictionary.
+// It is returned by Dictionary.Vendor.
+type VendorDictionary struct {
+	d *Dictionary
+	// name of the vendor, defaultVendor for the standard attribute space
+	vendor string
+}
+
+// Vendor returns the attribute space of the named vendor, which must be
+// registered with RegisterVendor before attributes can be registered in it.
+// The empty name refers to the standard attribute space.
+func (d *Dictionary) Vendor(name string) VendorDictionary {
+	if name == "" {
+		name = defaultVendor
+	}
+	return VendorDictionary{d: d, vendor: name}
+}
+
+// ID returns the vendor ID, or zero for the standard attribute space and for
+// vendors which are not registered.
+func (v VendorDictionary) ID() uint32 {
+	if v.vendor == defaultVendor {
+		return 0
+	}
+	return uint32(v.d.GetVendorId(v.vendor))
+}
+
+// values returns the attributes of the vendor. v.d.mu must be held.
+func (v VendorDictionary) values() *dictAttr {
+	return v.d.values[v.vendor]
+}
+
+// Register registers the AttributeCodec for the given attribute name and
+// vendor type. Attributes in the RFC 6929 extended attribute space are
+// registered with Dictionary.RegisterExtended.
+func (v VendorDictionary) Register(name string, t byte, codec AttributeCodec) error {
+	if v.vendor == defaultVendor && isExtendedType(t) {
+		return errors.New("radius: extended attributes must be registered with RegisterExtended")
+	}
+	return v.d.registerIn(v.vendor, &dictEntry{
+		Type:  t,
+		Name:  name,
+		Codec: codec,
+	})
+}
+
+// MustRegister is a helper for Register that panics if it returns an error.
+func (v VendorDictionary) MustRegister(name string, t byte, codec AttributeCodec) {
+	if err := v.Register(name, t, codec); err != nil {
+		panic(err)
+	}
+}
+
+// Attr returns a new *Attribute whose type is registered under the given name
+// in the vendor's attribute space. See Dictionary.Attr.
+func (v VendorDictionary) Attr(name string, value interface{}) (*Attribute, error) {
+	v.d.rlock()
+	var entry *dictEntry
+	if values := v.values(); values != nil {
+		entry = values.attributesByName[name]
+	}
+	v.d.runlock()
+	return newAttribute(entry, value)
+}
+
+// MustAttr is a helper for Attr that panics if Attr were to return an error.
+func (v VendorDictionary) MustAttr(name string, value interface{}) *Attribute {
+	attr, err := v.Attr(name, value)
+	if err != nil {
+		panic(err)
+	}
+	return attr
+}
+
+// entry returns the entry registered for the given type, or nil if there is
+// none.
+func (v VendorDictionary) entry(t byte) *dictEntry {
+	v.d.rlock()
+	defer v.d.runlock()
+	values := v.values()
+	if values == nil {
+		return nil
+	}
+	return values.attributesByType[attrKey{Type: t}]
+}
+
+// Name returns the registered name for the given vendor type. ok is false if
+// the given type is not registered.
+func (v VendorDictionary) Name(t byte) (name string, ok bool) {
+	entry := v.entry(t)
 	if entry == nil {
 		return
 	}
@@ -300,12 +764,15 @@ func (d *Dictionary) Name(t byte) (name string, ok bool) {
 	return
 }
 
-// Type returns the registered type for the given attribute name. ok is false
-// if the given name is not registered.
-func (d *Dictionary) Type(name string) (t byte, ok bool) {
-	d.mu.RLock()
-	entry := d.Values().attributesByName[name]
-	d.mu.RUnlock()
+// Type returns the registered vendor type for the given attribute name. ok is
+// false if the given name is not registered.
+func (v VendorDictionary) Type(name string) (t byte, ok bool) {
+	v.d.rlock()
+	var entry *dictEntry
+	if values := v.values(); values != nil {
+		entry = values.attributesByName[name]
+	}
+	v.d.runlock()
 	if entry == nil {
 		return
 	}
@@ -314,14 +781,130 @@ func (d *Dictionary) Type(name string) (t byte, ok bool) {
 	return
 }
 
-// Codec returns the AttributeCodec for the given registered type. nil is
-// returned if the given type is not registered.
-func (d *Dictionary) Codec(t byte) AttributeCodec {
-	d.mu.RLock()
-	entry := d.Values().attributesByType[t]
-	d.mu.RUnlock()
+// Codec returns the AttributeCodec for the given registered vendor type.
+// AttributeUnknown is returned if the given type is not registered.
+func (v VendorDictionary) Codec(t byte) AttributeCodec {
+	entry := v.entry(t)
 	if entry == nil {
 		return AttributeUnknown
 	}
 	return entry.Codec
 }
+
+// DictionaryVendor describes a vendor registered in a Dictionary.
+type DictionaryVendor struct {
+	Name string
+	ID   uint32
+	// Format is the format= option of the dictionary VENDOR line, such as
+	// "2,1", or "" for the default format.
+	Format string
+}
+
+// DictionaryAttribute describes an attribute registered in a Dictionary.
+type DictionaryAttribute struct {
+	Name string
+	// Aliases are the other names of the attribute, given by repeated
+	// dictionary ATTRIBUTE lines.
+	Aliases      []string
+	Vendor       uint32
+	Type         byte
+	ExtendedType byte
+	Codec        AttributeCodec
+	Concat       bool
+	// DataType and Flags are the data type and the flags of the dictionary
+	// ATTRIBUTE line the attribute was loaded from, such as "integer" and
+	// "has_tag". DataType is empty for attributes registered with Register.
+	DataType string
+	Flags    []string
+	// Values are the named values of an enumerated attribute, sorted by
+	// number. Of the names of the same number, the one used when printing
+	// values comes first.
+	Values []DictionaryValue
+}
+
+// DictionaryValue is a named value of an enumerated attribute.
+type DictionaryValue struct {
+	Name   string
+	Number uint32
+}
+
+// Vendors returns the vendors registered in d, sorted by ID.
+func (d *Dictionary) Vendors() []DictionaryVendor {
+	d.rlock()
+	defer d.runlock()
+	vendors := make([]DictionaryVendor, 0, len(d.vendorOrder))
+	for _, name := range d.vendorOrder {
+		vendors = append(vendors, DictionaryVendor{
+			Name:   name,
+			ID:     uint32(d.VendorId[name]),
+			Format: d.formats[name].String(),
+		})
+	}
+	return vendors
+}
+
+// Attributes returns the attributes registered in d: the standard attributes
+// first, followed by the attributes of each vendor in the order of Vendors.
+// The attributes of an attribute space are sorted by type.
+func (d *Dictionary) Attributes() []DictionaryAttribute {
+	d.rlock()
+	defer d.runlock()
+	var attrs []DictionaryAttribute
+	for _, vendor := range append([]string{defaultVendor}, d.vendorOrder...) {
+		values := d.values[vendor]
+		if values == nil {
+			continue
+		}
+		start := len(attrs)
+		for key, entry := range values.attributesByType {
+			attr := DictionaryAttribute{
+				Name:         entry.Name,
+				Vendor:       entry.Vendor,
+				Type:         key.Type,
+				ExtendedType: key.ExtendedType,
+				Codec:        entry.Codec,
+				Concat:       entry.Concat,
+				DataType:     entry.DataType,
+				Flags:        append([]string(nil), entry.Flags...),
+			}
+			for name, e := range values.attributesByName {
+				if e == entry && name != entry.Name {
+					attr.Aliases = append(attr.Aliases, name)
+				}
+			}
+			sort.Strings(attr.Aliases)
+			if enum, ok := entry.Codec.(*attributeEnum); ok {
+				attr.Values = enum.list()
+			}
+			attrs = append(attrs, attr)
+		}
+		sorted := attrs[start:]
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].Type != sorted[j].Type {
+				return sorted[i].Type < sorted[j].Type
+			}
+			return sorted[i].ExtendedType < sorted[j].ExtendedType
+		})
+	}
+	return attrs
+}
+
+// list returns the named values of e, as described by
+// DictionaryAttribute.Values.
+func (e *attributeEnum) list() []DictionaryValue {
+	values := make([]DictionaryValue, 0, len(e.values))
+	for name, number := range e.values {
+		values = append(values, DictionaryValue{Name: name, Number: number})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		a, b := values[i], values[j]
+		if a.Number != b.Number {
+			return a.Number < b.Number
+		}
+		if printed := e.names[a.Number]; a.Name == printed || b.Name == printed {
+			return a.Name == printed
+		}
+		return a.Name < b.Name
+	})
+	return values
+}
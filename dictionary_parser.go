@@ -0,0 +1,547 @@
+package radius
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DictionaryError is returned when a dictionary file cannot be loaded. It
+// contains the position of the offending line. Line is zero if the file
+// could not be opened.
+type DictionaryError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *DictionaryError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("radius: %s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("radius: %s:%d: %v", e.File, e.Line, e.Err)
+}
+
+// Unwrap returns the cause of the error, such as an fs.ErrNotExist error of
+// a missing file.
+func (e *DictionaryError) Unwrap() error {
+	return e.Err
+}
+
+// vendorFormat is the layout of the sub-attributes of a vendor's
+// Vendor-Specific attributes, as given by the format= option of a dictionary
+// VENDOR line.
+type vendorFormat struct {
+	// size of the vendor-type field: 1, 2 or 4
+	TypeSize int
+	// size of the vendor-length field: 0, 1 or 2
+	LengthSize int
+	// if set, a continuation byte follows the vendor-length field (WiMAX).
+	// Continued attributes are not reassembled.
+	Continuation bool
+}
+
+var defaultVendorFormat = vendorFormat{TypeSize: 1, LengthSize: 1}
+
+// String returns f in the form of the format= option of a VENDOR line, or ""
+// for the default format.
+func (f vendorFormat) String() string {
+	if f == defaultVendorFormat || f == (vendorFormat{}) {
+		return ""
+	}
+	s := strconv.Itoa(f.TypeSize) + "," + strconv.Itoa(f.LengthSize)
+	if f.Continuation {
+		s += ",c"
+	}
+	return s
+}
+
+// headerSize returns the size of a sub-attribute header.
+func (f vendorFormat) headerSize() int {
+	size := f.TypeSize + f.LengthSize
+	if f.Continuation {
+		size++
+	}
+	return size
+}
+
+// maximum nesting of $INCLUDE directives
+const maxIncludeDepth = 32
+
+// dictParser holds the state of loading a dictionary file, and the files it
+// includes, into a Dictionary.
+type dictParser struct {
+	d *Dictionary
+	// vendor of the current BEGIN-VENDOR block, "" outside of one
+	vendor string
+	// set inside a block whose attributes are not supported
+	skip bool
+	// names of attributes that were not registered, whose VALUE lines are
+	// ignored
+	skipped map[string]bool
+	depth   int
+	// open opens the named file, relative to the file including it, and
+	// returns its full name. from is empty for the first file. If nil,
+	// $INCLUDE is not supported.
+	open func(from, name string) (io.ReadCloser, string, error)
+}
+
+// LoadDicts loads the dictionary file at path, in the format used by
+// FreeRADIUS, into d. The following lines are supported:
+//
+//	$INCLUDE <file>        (relative to the including file; $INCLUDE- ignores
+//	                        missing files)
+//	ATTRIBUTE <name> <number> <type> [<flags>|<vendor>]
+//	VALUE <attribute> <name> <number>
+//	VENDOR <name> <id> [format=<t>,<l>[,c]]
+//	BEGIN-VENDOR <name>
+//	END-VENDOR <name>
+//
+// Attribute data types are mapped to the codec of the same name, such as
+// AttributeByte for byte; string and octets both use AttributeString. Other
+// data types can be added with RegisterDataType.
+//
+// Supported flags are has_tag, encrypt=1, encrypt=2, encrypt=3, concat,
+// array and virtual. Attributes that can never appear in a packet handled by
+// this package are ignored: server internal attributes (numbers above 255),
+// virtual attributes, nested TLV attributes, vendor attributes whose type
+// does not fit in a byte and Extended-Vendor-Specific attributes. VALUE lines
+// are ignored for attributes that cannot have named values (see
+// RegisterValue), such as array attributes.
+//
+// If the file cannot be loaded, a *DictionaryError describing the offending
+// line is returned, also when the file itself cannot be opened. Attributes
+// registered before the error stay registered.
+func (d *Dictionary) LoadDicts(path string) error {
+	p := &dictParser{
+		d: d,
+		open: func(from, name string) (io.ReadCloser, string, error) {
+			if from != "" && !filepath.IsAbs(name) {
+				name = filepath.Join(filepath.Dir(from), name)
+			}
+			file, err := os.Open(name)
+			return file, name, err
+		},
+	}
+	return p.parseFile("", path)
+}
+
+// LoadDictsFS is like LoadDicts, but loads the dictionary file name from
+// fsys, such as an embed.FS. $INCLUDE lines are resolved within fsys.
+func (d *Dictionary) LoadDictsFS(fsys fs.FS, name string) error {
+	p := &dictParser{
+		d: d,
+		open: func(from, name string) (io.ReadCloser, string, error) {
+			if from != "" {
+				name = includePath(path.Dir(from), name)
+			}
+			file, err := fsys.Open(name)
+			return file, name, err
+		},
+	}
+	return p.parseFile("", name)
+}
+
+// includePath joins name to dir like path.Join, except that dir is ignored if
+// name is absolute. Absolute names are made relative to the root of an fs.FS.
+func includePath(dir, name string) string {
+	if strings.HasPrefix(name, "/") {
+		return path.Clean(name[1:])
+	}
+	return path.Join(dir, name)
+}
+
+// LoadDictsReader is like LoadDicts, but reads the dictionary from r. name is
+// only used in the position of errors. $INCLUDE lines are not supported.
+func (d *Dictionary) LoadDictsReader(r io.Reader, name string) error {
+	p := &dictParser{d: d}
+	return p.parse(r, name)
+}
+
+func (p *dictParser) parseFile(from, name string) error {
+	file, name, err := p.open(from, name)
+	if err != nil {
+		if from == "" {
+			return &DictionaryError{File: name, Err: err}
+		}
+		// The error is positioned at the $INCLUDE line.
+		return err
+	}
+	defer file.Close()
+	return p.parse(file, name)
+}
+
+// parse parses the dictionary read from r, whose file name is name.
+func (p *dictParser) parse(r io.Reader, name string) error {
+	vendor, skip := p.vendor, p.skip
+	p.vendor, p.skip = "", false
+	defer func() {
+		p.vendor, p.skip = vendor, skip
+	}()
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i > -1 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := p.parseLine(fields, name); err != nil {
+			if _, ok := err.(*DictionaryError); ok {
+				return err
+			}
+			return &DictionaryError{File: name, Line: line, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &DictionaryError{File: name, Line: line, Err: err}
+	}
+	if p.vendor != "" {
+		return &DictionaryError{File: name, Line: line, Err: errors.New("BEGIN-VENDOR " + p.vendor + " without END-VENDOR")}
+	}
+	return nil
+}
+
+func (p *dictParser) parseLine(fields []string, name string) error {
+	switch strings.ToUpper(fields[0]) {
+	case "$INCLUDE", "$INCLUDE-":
+		if len(fields) != 2 {
+			return errors.New("invalid $INCLUDE line")
+		}
+		if p.open == nil {
+			return errors.New("$INCLUDE is not supported")
+		}
+		if p.depth >= maxIncludeDepth {
+			return errors.New("too many nested $INCLUDE lines")
+		}
+		p.depth++
+		err := p.parseFile(name, fields[1])
+		p.depth--
+		if err != nil && fields[0] == "$INCLUDE-" && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	case "ATTRIBUTE":
+		return p.parseAttribute(fields)
+	case "VALUE":
+		return p.parseValue(fields)
+	case "VENDOR":
+		return p.parseVendor(fields)
+	case "BEGIN-VENDOR":
+		if len(fields) != 2 && len(fields) != 3 {
+			return errors.New("invalid BEGIN-VENDOR line")
+		}
+		if p.vendor != "" {
+			return errors.New("nested BEGIN-VENDOR")
+		}
+		if p.d.GetVendorId(fields[1]) == 0 {
+			return errors.New("unknown vendor " + strconv.Quote(fields[1]))
+		}
+		p.vendor = fields[1]
+		// format=Extended-Vendor-Specific-N puts the attributes in the
+		// RFC 6929 extended vendor space, which is not supported.
+		p.skip = len(fields) == 3
+		return nil
+	case "END-VENDOR":
+		if len(fields) != 2 {
+			return errors.New("invalid END-VENDOR line")
+		}
+		if fields[1] != p.vendor {
+			return errors.New("END-VENDOR " + fields[1] + " does not match BEGIN-VENDOR")
+		}
+		p.vendor, p.skip = "", false
+		return nil
+	}
+	return errors.New("unknown keyword " + strconv.Quote(fields[0]))
+}
+
+// parseNumber parses a decimal or 0x prefixed hexadecimal number.
+func parseNumber(s string, bitSize int) (uint64, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return strconv.ParseUint(s[2:], 16, bitSize)
+	}
+	return strconv.ParseUint(s, 10, bitSize)
+}
+
+func (p *dictParser) parseVendor(fields []string) error {
+	if len(fields) != 3 && len(fields) != 4 {
+		return errors.New("invalid VENDOR line")
+	}
+	id, err := parseNumber(fields[2], 32)
+	if err != nil || id == 0 {
+		return errors.New("invalid vendor id " + strconv.Quote(fields[2]))
+	}
+
+	format := defaultVendorFormat
+	if len(fields) == 4 {
+		if !strings.HasPrefix(fields[3], "format=") {
+			return errors.New("invalid vendor option " + strconv.Quote(fields[3]))
+		}
+		parts := strings.Split(strings.TrimPrefix(fields[3], "format="), ",")
+		if len(parts) < 2 || len(parts) > 3 {
+			return errors.New("invalid vendor format " + strconv.Quote(fields[3]))
+		}
+		format.TypeSize, _ = strconv.Atoi(parts[0])
+		format.LengthSize, err = strconv.Atoi(parts[1])
+		if (format.TypeSize != 1 && format.TypeSize != 2 && format.TypeSize != 4) ||
+			err != nil || format.LengthSize < 0 || format.LengthSize > 2 {
+			return errors.New("invalid vendor format " + strconv.Quote(fields[3]))
+		}
+		if len(parts) == 3 {
+			if parts[2] != "c" || format.TypeSize != 1 || format.LengthSize != 1 {
+				return errors.New("invalid vendor format " + strconv.Quote(fields[3]))
+			}
+			format.Continuation = true
+		}
+	}
+
+	if existing := p.d.GetVendorId(fields[1]); existing != 0 {
+		if uint64(existing) != id {
+			return errors.New("vendor " + strconv.Quote(fields[1]) + " already registered with a different id")
+		}
+		return nil
+	}
+	if !p.d.addVendor(fields[1], int(id), format) {
+		return errors.New("vendor " + strconv.Quote(fields[1]) + " could not be registered")
+	}
+	return nil
+}
+
+func (p *dictParser) parseValue(fields []string) error {
+	if len(fields) != 4 {
+		return errors.New("invalid VALUE line")
+	}
+	if p.skipped[fields[1]] {
+		return nil
+	}
+	value, err := parseNumber(fields[3], 32)
+	if err != nil {
+		return errors.New("invalid value " + strconv.Quote(fields[3]))
+	}
+	err = p.d.RegisterValue(fields[1], fields[2], uint32(value))
+	if err == errNotEnumerable {
+		// FreeRADIUS also names values of other data types, which are
+		// not supported.
+		return nil
+	}
+	return err
+}
+
+// skipAttribute records that the attribute was not registered.
+func (p *dictParser) skipAttribute(name string) error {
+	if p.skipped == nil {
+		p.skipped = make(map[string]bool)
+	}
+	p.skipped[name] = true
+	return nil
+}
+
+// dataType returns the AttributeCodec of a dictionary data type. container
+// is true for the types of the Vendor-Specific and extended attributes, which
+// are not registered themselves. Data types registered with RegisterDataType
+// take precedence over the built-in ones.
+func (d *Dictionary) dataType(typeName string) (codec AttributeCodec, container bool, err error) {
+	d.rlock()
+	codec = d.types[typeName]
+	d.runlock()
+	if codec != nil {
+		return
+	}
+
+	switch typeName {
+	case "string":
+		codec = AttributeString
+	case "integer", "uint32":
+		codec = AttributeInteger
+	case "ipaddr":
+		codec = AttributeAddress
+	case "octets":
+		codec = AttributeString
+	case "date":
+		codec = AttributeTime
+	case "ipv6addr":
+		codec = AttributeIPv6Address
+	case "ipv6prefix":
+		codec = AttributeIPv6Prefix
+	case "ifid":
+		codec = AttributeInterfaceID
+	case "integer64", "uint64":
+		codec = AttributeInteger64
+	case "ipv4prefix":
+		codec = AttributeIPv4Prefix
+	case "byte", "uint8":
+		codec = AttributeByte
+	case "short", "uint16":
+		codec = AttributeShort
+	case "signed", "int32":
+		codec = AttributeSigned
+	case "ether":
+		codec = AttributeEther
+	case "abinary":
+		codec = AttributeABinary
+	case "combo-ip":
+		codec = AttributeComboIP
+	case "tlv":
+		codec = AttributeTLV
+	case "vsa":
+		codec = AttributeString
+	case "extended", "long-extended", "evs":
+		container = true
+	default:
+		err = errors.New("unknown attribute type " + strconv.Quote(typeName))
+	}
+	return
+}
+
+func (p *dictParser) parseAttribute(fields []string) error {
+	if len(fields) != 4 && len(fields) != 5 {
+		return errors.New("invalid ATTRIBUTE line")
+	}
+	name, number, typeName := fields[1], fields[2], fields[3]
+
+	vendor := p.vendor
+	var flags []string
+	if len(fields) == 5 {
+		if !strings.Contains(fields[4], "=") && p.d.GetVendorId(fields[4]) != 0 {
+			// old format: the fifth field is the vendor
+			if vendor != "" {
+				return errors.New("vendor given inside of BEGIN-VENDOR block")
+			}
+			vendor = fields[4]
+		} else {
+			flags = strings.Split(fields[4], ",")
+		}
+	}
+
+	// The size of fixed length types, as in octets[16], is not enforced.
+	if i := strings.IndexByte(typeName, '['); i > 0 && strings.HasSuffix(typeName, "]") {
+		typeName = typeName[:i]
+	}
+
+	codec, container, err := p.d.dataType(typeName)
+	if err != nil {
+		return err
+	}
+
+	var (
+		hasTag  bool
+		encrypt string
+		concat  bool
+		virtual bool
+	)
+	for _, flag := range flags {
+		switch {
+		case flag == "has_tag":
+			hasTag = true
+		case flag == "concat":
+			concat = true
+		case flag == "array":
+			// arrays of values are kept as raw octets
+			codec = AttributeString
+		case flag == "virtual":
+			virtual = true
+		case strings.HasPrefix(flag, "encrypt="):
+			encrypt = strings.TrimPrefix(flag, "encrypt=")
+		default:
+			return errors.New("unknown attribute flag " + strconv.Quote(flag))
+		}
+	}
+
+	switch encrypt {
+	case "":
+	case "0":
+	case "1":
+		codec = rfc2865UserPassword{}
+	case "2":
+		codec = rfc2548SaltEncrypted{}
+	case "3":
+		codec = ascendSecret{}
+	default:
+		return errors.New("unknown attribute encryption " + strconv.Quote(encrypt))
+	}
+	if hasTag {
+		switch codec {
+		case AttributeInteger:
+			codec = AttributeTaggedInteger
+		case AttributeString:
+			codec = AttributeTaggedText
+		case rfc2548SaltEncrypted{}:
+			codec = rfc2868TunnelPassword{}
+		default:
+			return errors.New("has_tag is not supported for type " + strconv.Quote(typeName))
+		}
+	}
+	if concat && codec != AttributeString {
+		return errors.New("concat is only supported for octets attributes")
+	}
+
+	if p.skip || virtual || container {
+		return p.skipAttribute(name)
+	}
+
+	parts := strings.Split(number, ".")
+	var numbers []uint64
+	for _, part := range parts {
+		n, err := parseNumber(part, 32)
+		if err != nil {
+			return errors.New("invalid attribute number " + strconv.Quote(number))
+		}
+		numbers = append(numbers, n)
+	}
+
+	entry := &dictEntry{
+		Name:     name,
+		Codec:    codec,
+		Concat:   concat,
+		DataType: typeName,
+		Flags:    flags,
+	}
+	switch {
+	case len(numbers) > 2:
+		// nested TLV
+		return p.skipAttribute(name)
+	case vendor != "":
+		if len(numbers) != 1 || numbers[0] > 255 {
+			return p.skipAttribute(name)
+		}
+		entry.Type = byte(numbers[0])
+	case numbers[0] > 255:
+		// server internal attribute
+		return p.skipAttribute(name)
+	case isExtendedType(byte(numbers[0])):
+		if len(numbers) != 2 || numbers[1] > 255 {
+			return errors.New("invalid extended attribute number " + strconv.Quote(number))
+		}
+		if numbers[1] == 26 {
+			// Extended-Vendor-Specific
+			return p.skipAttribute(name)
+		}
+		entry.Type = byte(numbers[0])
+		entry.ExtendedType = byte(numbers[1])
+	case len(numbers) != 1:
+		// nested TLV
+		return p.skipAttribute(name)
+	default:
+		entry.Type = byte(numbers[0])
+	}
+
+	err = p.d.registerIn(vendor, entry)
+	if err == errAttributeRegistered {
+		// The same attribute may be defined more than once, and attributes
+		// may have multiple names.
+		return p.d.registerAlias(vendor, name, attrKey{Type: entry.Type, ExtendedType: entry.ExtendedType})
+	}
+	return err
+}
@@ -0,0 +1,369 @@
+package radius_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/runner-mei/radius"
+)
+
+func writeDictionaries(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func Test_LoadDicts(t *testing.T) {
+	dir := writeDictionaries(t, map[string]string{
+		"dictionary": `# Test dictionary
+$INCLUDE vendors/dictionary.example
+$INCLUDE- dictionary.local
+
+ATTRIBUTE	User-Name		1	string
+ATTRIBUTE	User-Password		2	string	encrypt=1
+ATTRIBUTE	Service-Type		6	integer	# trailing comment
+VALUE	Service-Type		Framed-User		2
+ATTRIBUTE	Filter-Id		11	string
+VALUE	Filter-Id		Ignored			1
+ATTRIBUTE	Vendor-Specific		26	vsa
+ATTRIBUTE	Tunnel-Type		64	integer	has_tag
+ATTRIBUTE	Extended-Attribute-1	241	extended
+ATTRIBUTE	Frag-Status		241.1	integer
+ATTRIBUTE	Auth-Type		1000	integer
+VALUE	Auth-Type		Local			0
+`,
+		"vendors/dictionary.example": `VENDOR		Example		32473	format=2,1
+BEGIN-VENDOR	Example
+ATTRIBUTE	Example-Mode		1	integer
+VALUE	Example-Mode		Turbo			0x10
+ATTRIBUTE	Example-Data		2	octets	concat
+ATTRIBUTE	Example-List		3	integer	array
+VALUE	Example-List		Ignored			1
+END-VENDOR	Example
+`,
+	})
+
+	dict := &radius.Dictionary{}
+	if err := dict.LoadDicts(filepath.Join(dir, "dictionary")); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("secret")
+	p := radius.New(radius.CodeAccessRequest, secret)
+	p.Dictionary = dict
+	p.Add("User-Name", "nemo")
+	p.Add("User-Password", "arctangent")
+	p.Add("Service-Type", "Framed-User")
+	p.Add("Example-Mode", "Turbo")
+	p.Add("Frag-Status", uint32(1))
+
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vsa := []byte{26, 13, 0, 0, 0x7e, 0xd9, 0, 1, 7, 0, 0, 0, 0x10}
+	if !bytes.Contains(b, vsa) {
+		t.Fatalf("expecting VSA in format 2,1, got %x", b)
+	}
+
+	q, err := radius.Parse(b, secret, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := q.String("Example-Mode"); s != "Turbo" {
+		t.Fatalf("expecting Example-Mode Turbo, got %q", s)
+	}
+	if s := q.String("Service-Type"); s != "Framed-User" {
+		t.Fatalf("expecting Service-Type Framed-User, got %q", s)
+	}
+	if s := q.String("User-Password"); s != "arctangent" {
+		t.Fatalf("expecting User-Password arctangent, got %q", s)
+	}
+	if v := q.Value("Frag-Status"); v != uint32(1) {
+		t.Fatalf("expecting Frag-Status 1, got %v", v)
+	}
+}
+
+func Test_LoadDicts_Errors(t *testing.T) {
+	tests := []struct {
+		Content string
+		Line    int
+	}{
+		{"ATTRIBUTE User-Name 1 string\nATTRIBUTE Broken 2 nosuchtype\n", 2},
+		{"ATTRIBUTE User-Name 1 string has_tag,foo\n", 1},
+		{"VENDOR Example 32473\nBEGIN-VENDOR Example\nATTRIBUTE Example-Mode 1 integer\n", 3},
+		{"\nEND-VENDOR Example\n", 2},
+		{"# nothing\nVALUE Unknown Name 1\n", 2},
+		{"NOT-A-KEYWORD\n", 1},
+	}
+
+	for _, test := range tests {
+		dir := writeDictionaries(t, map[string]string{"dictionary": test.Content})
+		path := filepath.Join(dir, "dictionary")
+		err := (&radius.Dictionary{}).LoadDicts(path)
+		dictErr, ok := err.(*radius.DictionaryError)
+		if !ok {
+			t.Fatalf("%q: expecting *DictionaryError, got %v", test.Content, err)
+		}
+		if dictErr.File != path || dictErr.Line != test.Line {
+			t.Fatalf("%q: expecting error at %s:%d, got %v", test.Content, path, test.Line, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "missing")
+	err := (&radius.Dictionary{}).LoadDicts(path)
+	if dictErr, ok := err.(*radius.DictionaryError); !ok || dictErr.File != path {
+		t.Fatalf("expecting *DictionaryError for %s, got %v", path, err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expecting fs.ErrNotExist, got %v", err)
+	}
+}
+
+func Test_LoadDictsFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dicts/dictionary":                {Data: []byte("$INCLUDE vendor/dictionary.example\n")},
+		"dicts/vendor/dictionary.example": {Data: []byte("VENDOR Example 32473\nATTRIBUTE Example-Mode 1 integer Example\n")},
+	}
+	dict := &radius.Dictionary{}
+	if err := dict.LoadDictsFS(fsys, "dicts/dictionary"); err != nil {
+		t.Fatal(err)
+	}
+	if attr, err := dict.Attr("Example-Mode", uint32(1)); err != nil || attr.Vendor != 32473 {
+		t.Fatalf("expecting Example-Mode from vendor 32473, got %v, %v", attr, err)
+	}
+
+	err := dict.LoadDictsReader(strings.NewReader("VALUE Example-Mode Turbo 16\n$INCLUDE other\n"), "reader")
+	if dictErr, ok := err.(*radius.DictionaryError); !ok || dictErr.File != "reader" || dictErr.Line != 2 {
+		t.Fatalf("expecting error at reader:2, got %v", err)
+	}
+}
+
+func Test_DictionarySnapshot(t *testing.T) {
+	dict := &radius.Dictionary{}
+	dict.MustRegister("User-Name", 1, radius.AttributeText)
+	dict.RegisterVendor("Example", 32473)
+	dict.Vendor("Example").MustRegister("Example-Mode", 1, radius.AttributeInteger)
+
+	snapshot := dict.Snapshot()
+	if err := snapshot.Register("Reply-Message", 18, radius.AttributeText); err == nil {
+		t.Fatal("expecting registration in snapshot to fail")
+	}
+	dict.MustRegister("Reply-Message", 18, radius.AttributeText)
+	if _, ok := snapshot.Type("Reply-Message"); ok {
+		t.Fatal("expecting snapshot to be unaffected by later registrations")
+	}
+
+	if name, ok := snapshot.Vendor("Example").Name(1); !ok || name != "Example-Mode" {
+		t.Fatalf("expecting Example-Mode, got %q", name)
+	}
+	if name, ok := snapshot.Name(1); !ok || name != "User-Name" {
+		t.Fatalf("expecting User-Name, got %q", name)
+	}
+	attr, err := snapshot.Vendor("Example").Attr("Example-Mode", uint32(3))
+	if err != nil || attr.Vendor != 32473 || attr.Type != 1 {
+		t.Fatalf("unexpected attribute %v, %v", attr, err)
+	}
+}
+
+func Test_DictionaryVendorOrder(t *testing.T) {
+	// A name defined by several vendors refers to the attribute of the vendor
+	// with the lowest ID, whatever the order of registration.
+	for i := 0; i < 10; i++ {
+		dict := &radius.Dictionary{}
+		dict.RegisterVendor("Second", 200)
+		dict.RegisterVendor("First", 100)
+		dict.Vendor("Second").MustRegister("Example-Mode", 2, radius.AttributeInteger)
+		dict.Vendor("First").MustRegister("Example-Mode", 1, radius.AttributeInteger)
+		if attr := dict.MustAttr("Example-Mode", uint32(1)); attr.Vendor != 100 || attr.Type != 1 {
+			t.Fatalf("expecting Example-Mode of vendor 100, got %d/%d", attr.Vendor, attr.Type)
+		}
+	}
+}
+
+func Test_DictionaryAttributes(t *testing.T) {
+	const text = `
+VENDOR Example-Wide 2000 format=2,1
+VENDOR Example 1000
+ATTRIBUTE Example-Count 2 integer Example
+ATTRIBUTE Example-Total 2 integer Example
+ATTRIBUTE Example-Name 1 string Example
+ATTRIBUTE Example-Tunnel 3 integer has_tag
+ATTRIBUTE Example-Wide-Mode 1 short Example-Wide
+VALUE Example-Count Many 2
+VALUE Example-Count One 1
+VALUE Example-Total Single 1
+`
+	dict := &radius.Dictionary{}
+	dict.MustRegister("Example-Reply", 4, radius.AttributeString)
+	if err := dict.LoadDictsReader(strings.NewReader(text), "dictionary.example"); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedVendors := []radius.DictionaryVendor{
+		{Name: "Example", ID: 1000},
+		{Name: "Example-Wide", ID: 2000, Format: "2,1"},
+	}
+	if vendors := dict.Vendors(); !reflect.DeepEqual(vendors, expectedVendors) {
+		t.Fatalf("expecting vendors %+v, got %+v", expectedVendors, vendors)
+	}
+
+	attrs := dict.Attributes()
+	var names []string
+	for _, attr := range attrs {
+		names = append(names, attr.Name)
+	}
+	expectedNames := []string{"Example-Tunnel", "Example-Reply", "Example-Name", "Example-Count", "Example-Wide-Mode"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("expecting attributes %q, got %q", expectedNames, names)
+	}
+
+	tunnel := attrs[0]
+	if tunnel.DataType != "integer" || !reflect.DeepEqual(tunnel.Flags, []string{"has_tag"}) || tunnel.Codec != radius.AttributeTaggedInteger {
+		t.Fatalf("unexpected Example-Tunnel %+v", tunnel)
+	}
+	if reply := attrs[1]; reply.DataType != "" || reply.Codec != radius.AttributeString {
+		t.Fatalf("unexpected Example-Reply %+v", reply)
+	}
+	count := attrs[3]
+	if count.Vendor != 1000 || count.Type != 2 || !reflect.DeepEqual(count.Aliases, []string{"Example-Total"}) {
+		t.Fatalf("unexpected Example-Count %+v", count)
+	}
+	expectedValues := []radius.DictionaryValue{{Name: "One", Number: 1}, {Name: "Single", Number: 1}, {Name: "Many", Number: 2}}
+	if !reflect.DeepEqual(count.Values, expectedValues) {
+		t.Fatalf("expecting values %+v, got %+v", expectedValues, count.Values)
+	}
+}
+
+func Test_DictionaryDeprecatedVendor(t *testing.T) {
+	dict := &radius.Dictionary{}
+	for _, line := range []string{
+		"VENDOR Example 32473",
+		"BEGIN-VENDOR Example",
+		"ATTRIBUTE Example-Mode 1 integer",
+		"END-VENDOR Example",
+		"ATTRIBUTE User-Name 1 string",
+	} {
+		arr := strings.Fields(line)
+		if !dict.ParseVendor(arr) && !dict.ParseBeginVendor(arr) && !dict.ParseEndVendor(arr) && !dict.ParseAttrs(arr) {
+			t.Fatalf("could not parse %q", line)
+		}
+	}
+	if name, ok := dict.Vendor("Example").Name(1); !ok || name != "Example-Mode" {
+		t.Fatalf("expecting Example-Mode in vendor Example, got %q", name)
+	}
+	if name, ok := dict.Name(1); !ok || name != "User-Name" {
+		t.Fatalf("expecting User-Name, got %q", name)
+	}
+	dict.SwitchVendor("Example")
+	if dict.Values() == nil {
+		t.Fatal("expecting the attributes of vendor Example")
+	}
+	if name, ok := dict.Name(1); !ok || name != "User-Name" {
+		t.Fatal("expecting SwitchVendor not to affect Name")
+	}
+}
+
+type upperText struct{}
+
+func (upperText) Decode(p *radius.Packet, value []byte) (interface{}, error) {
+	return strings.ToUpper(string(value)), nil
+}
+
+func (upperText) Encode(p *radius.Packet, value interface{}) ([]byte, error) {
+	return []byte(value.(string)), nil
+}
+
+func Test_DictionaryDataTypes(t *testing.T) {
+	dict := &radius.Dictionary{}
+	if err := dict.RegisterDataType("upper", upperText{}); err != nil {
+		t.Fatal(err)
+	}
+	err := dict.LoadDictsReader(strings.NewReader(`
+ATTRIBUTE	Example-Byte	1	byte
+VALUE	Example-Byte	High	200
+ATTRIBUTE	Example-Short	2	short
+ATTRIBUTE	Example-Signed	3	signed
+ATTRIBUTE	Example-Ether	4	ether
+ATTRIBUTE	Example-Combo	5	combo-ip
+ATTRIBUTE	Example-Filter	6	abinary
+ATTRIBUTE	Example-Upper	7	upper
+`), "types")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := radius.New(radius.CodeAccessAccept, []byte("secret"))
+	p.Dictionary = dict
+	for _, attr := range []struct {
+		Name  string
+		Value interface{}
+	}{
+		{"Example-Byte", "High"},
+		{"Example-Short", 1812},
+		{"Example-Signed", -5},
+		{"Example-Ether", "00:11:22:33:44:55"},
+		{"Example-Combo", net.ParseIP("2001:db8::1")},
+		{"Example-Filter", []byte{1, 2, 3}},
+		{"Example-Upper", "shout"},
+	} {
+		if err := p.Add(attr.Name, attr.Value); err != nil {
+			t.Fatalf("%s: %v", attr.Name, err)
+		}
+	}
+	if err := p.Add("Example-Byte", 256); err == nil {
+		t.Fatal("expecting byte value 256 to be rejected")
+	}
+	if err := p.Add("Example-Signed", uint32(1<<31)); err == nil {
+		t.Fatal("expecting signed value 2147483648 to be rejected")
+	}
+
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := radius.Parse(b, []byte("secret"), dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := q.Value("Example-Byte"); v != uint8(200) || q.String("Example-Byte") != "High" {
+		t.Fatalf("unexpected Example-Byte %v", v)
+	}
+	if v := q.Value("Example-Short"); v != uint16(1812) {
+		t.Fatalf("unexpected Example-Short %v", v)
+	}
+	if v := q.Value("Example-Signed"); v != int32(-5) {
+		t.Fatalf("unexpected Example-Signed %v", v)
+	}
+	if v := q.String("Example-Ether"); v != "00:11:22:33:44:55" {
+		t.Fatalf("unexpected Example-Ether %v", v)
+	}
+	if v := q.String("Example-Combo"); v != "2001:db8::1" {
+		t.Fatalf("unexpected Example-Combo %v", v)
+	}
+	if v := q.Value("Example-Upper"); v != "SHOUT" {
+		t.Fatalf("unexpected Example-Upper %v", v)
+	}
+
+	// abinary is not a string type.
+	err = dict.LoadDictsReader(strings.NewReader("ATTRIBUTE Example-Tagged-Filter 8 abinary has_tag\n"), "types")
+	if err == nil {
+		t.Fatal("expecting has_tag to be rejected for abinary")
+	}
+}
@@ -6,6 +6,19 @@
 // Builtin dictionary. Each row contains the attributes' name, type (number),
 // and Go data type.
 //
+// Enumerated attributes, such as Service-Type and Acct-Status-Type, also
+// accept the names of their values (e.g. "Framed-User") when added to a
+// packet, and Packet.String returns the name of their value.
+//
+// Values of EAP-Message, and of other attributes marked with
+// Dictionary.SetConcat or the dictionary concat flag, may be longer than 253
+// bytes. They are split across consecutive attributes when encoded and joined
+// into a single Attribute when parsed.
+//
+// Additional attributes can be loaded from dictionary files in the format used
+// by FreeRADIUS with Dictionary.LoadDicts, LoadDictsFS and LoadDictsReader.
+// The dictionaries package bundles common vendor dictionaries.
+//
 // The following attributes are defined by RFC 2865:
 //
 //  User-Name                 1   string
@@ -64,4 +77,78 @@
 //  Acct-Terminate-Cause   49  uint32
 //  Acct-Multi-Session-Id  50  string
 //  Acct-Link-Count        51  uint32
+//
+// The following attributes are defined by RFC 2868. Their values are Tagged,
+// whose Value has the given Go data type:
+//
+//  Tunnel-Type              64  uint32
+//  Tunnel-Medium-Type       65  uint32
+//  Tunnel-Client-Endpoint   66  string
+//  Tunnel-Server-Endpoint   67  string
+//  Tunnel-Password          69  string
+//  Tunnel-Private-Group-Id  81  string
+//  Tunnel-Assignment-Id     82  string
+//  Tunnel-Preference        83  uint32
+//  Tunnel-Client-Auth-Id    90  string
+//  Tunnel-Server-Auth-Id    91  string
+//
+// The following attributes are defined by RFC 3162:
+//
+//  NAS-IPv6-Address     95   net.IP
+//  Framed-Interface-Id  96   []byte
+//  Framed-IPv6-Prefix   97   *net.IPNet
+//  Login-IPv6-Host      98   net.IP
+//  Framed-IPv6-Route    99   string
+//  Framed-IPv6-Pool     100  string
+//
+// The following attributes are defined by RFC 3579:
+//
+//  EAP-Message            79  []byte
+//  Message-Authenticator  80  []byte
+//
+// The following attribute is defined by RFC 4818:
+//
+//  Delegated-IPv6-Prefix  123  *net.IPNet
+//
+// The following attributes are defined by RFC 6911:
+//
+//  Framed-IPv6-Address         168  net.IP
+//  DNS-Server-IPv6-Address     169  net.IP
+//  Route-IPv6-Information      170  *net.IPNet
+//  Delegated-IPv6-Prefix-Pool  171  string
+//  Stateful-IPv6-Address-Pool  172  string
+//
+// The following Microsoft vendor-specific attributes (vendor 311) are defined
+// by RFC 2548:
+//
+//  MS-CHAP-Response               1   []byte
+//  MS-CHAP-Error                  2   []byte
+//  MS-CHAP-CPW-1                  3   []byte
+//  MS-CHAP-CPW-2                  4   []byte
+//  MS-CHAP-LM-Enc-PW              5   []byte
+//  MS-CHAP-NT-Enc-PW              6   []byte
+//  MS-MPPE-Encryption-Policy      7   uint32
+//  MS-MPPE-Encryption-Types       8   uint32
+//  MS-RAS-Vendor                  9   uint32
+//  MS-CHAP-Domain                 10  []byte
+//  MS-CHAP-Challenge              11  []byte
+//  MS-BAP-Usage                   13  uint32
+//  MS-Link-Utilization-Threshold  14  uint32
+//  MS-Link-Drop-Time-Limit        15  uint32
+//  MS-MPPE-Send-Key               16  []byte
+//  MS-MPPE-Recv-Key               17  []byte
+//  MS-Old-ARAP-Password           19  []byte
+//  MS-New-ARAP-Password           20  []byte
+//  MS-ARAP-PW-Change-Reason       21  uint32
+//  MS-Filter                      22  []byte
+//  MS-Acct-Auth-Type              23  uint32
+//  MS-Acct-EAP-Type               24  uint32
+//  MS-CHAP2-Response              25  []byte
+//  MS-CHAP2-Success               26  []byte
+//  MS-CHAP2-CPW                   27  []byte
+//  MS-Primary-DNS-Server          28  net.IP
+//  MS-Secondary-DNS-Server        29  net.IP
+//  MS-Primary-NBNS-Server         30  net.IP
+//  MS-Secondary-NBNS-Server       31  net.IP
+//  MS-ARAP-Challenge              33  []byte
 package radius
@@ -0,0 +1,60 @@
+package radius
+
+// VendorFreeRADIUS is the vendor ID of the FreeRADIUS vendor-specific
+// attributes, which include the server statistics answered to Status-Server
+// packets.
+const VendorFreeRADIUS = 11344
+
+// Values of the FreeRADIUS-Statistics-Type attribute, which are combined to
+// select the statistics of a Status-Server response.
+const (
+	StatisticsAuthentication uint32 = 0x01
+	StatisticsAccounting     uint32 = 0x02
+	StatisticsInternal       uint32 = 0x10
+	StatisticsClient         uint32 = 0x20
+	StatisticsServer         uint32 = 0x40
+)
+
+func init() {
+	builtinOnce.Do(initDictionary)
+	Builtin.RegisterVendor("FreeRADIUS", VendorFreeRADIUS)
+	fr := Builtin.Vendor("FreeRADIUS")
+	fr.MustRegister("FreeRADIUS-Statistics-Type", 127, AttributeInteger)
+
+	fr.MustRegister("FreeRADIUS-Total-Access-Requests", 128, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Access-Accepts", 129, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Access-Rejects", 130, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Access-Challenges", 131, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Auth-Responses", 132, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Auth-Duplicate-Requests", 133, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Auth-Malformed-Requests", 134, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Auth-Invalid-Requests", 135, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Auth-Dropped-Requests", 136, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Auth-Unknown-Types", 137, AttributeInteger)
+
+	fr.MustRegister("FreeRADIUS-Total-Accounting-Requests", 148, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Accounting-Responses", 149, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Acct-Duplicate-Requests", 150, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Acct-Malformed-Requests", 151, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Acct-Invalid-Requests", 152, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Acct-Dropped-Requests", 153, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Total-Acct-Unknown-Types", 154, AttributeInteger)
+
+	fr.MustRegister("FreeRADIUS-Stats-Client-IP-Address", 167, AttributeAddress)
+	fr.MustRegister("FreeRADIUS-Stats-Client-Number", 168, AttributeInteger)
+	fr.MustRegister("FreeRADIUS-Stats-Start-Time", 176, AttributeTime)
+	fr.MustRegister("FreeRADIUS-Stats-HUP-Time", 177, AttributeTime)
+
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "None", 0)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Authentication", StatisticsAuthentication)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Accounting", StatisticsAccounting)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Proxy-Authentication", 0x04)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Proxy-Accounting", 0x08)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Internal", StatisticsInternal)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Client", StatisticsClient)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Server", StatisticsServer)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Home-Server", 0x80)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Auth-Acct", 0x03)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "Proxy-Auth-Acct", 0x0c)
+	Builtin.MustRegisterValue("FreeRADIUS-Statistics-Type", "All", 0x1f)
+}
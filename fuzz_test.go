@@ -0,0 +1,123 @@
+package radius_test
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/runner-mei/radius"
+)
+
+// pcapngPayloads returns the UDP payloads of the IPv4 packets captured in a
+// pcapng file with Ethernet link types.
+func pcapngPayloads(t testing.TB, path string) [][]byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payloads [][]byte
+	order := binary.ByteOrder(binary.LittleEndian)
+	for len(data) >= 12 {
+		blockType := order.Uint32(data[0:4])
+		if blockType == 0x0a0d0d0a && order.Uint32(data[8:12]) != 0x1a2b3c4d {
+			order = binary.BigEndian
+		}
+		length := int(order.Uint32(data[4:8]))
+		if length < 12 || length > len(data) {
+			t.Fatal("invalid pcapng block length")
+		}
+		block := data[:length]
+		data = data[length:]
+
+		// Enhanced Packet Block
+		if blockType != 6 || len(block) < 28 {
+			continue
+		}
+		captured := int(order.Uint32(block[20:24]))
+		if 28+captured > len(block) {
+			t.Fatal("invalid pcapng packet length")
+		}
+		frame := block[28 : 28+captured]
+		if len(frame) < 14+20 || binary.BigEndian.Uint16(frame[12:14]) != 0x0800 {
+			continue
+		}
+		ip := frame[14:]
+		headerLength := int(ip[0]&0x0f) * 4
+		if ip[9] != 17 || len(ip) < headerLength+8 {
+			continue
+		}
+		payloads = append(payloads, ip[headerLength+8:])
+	}
+	return payloads
+}
+
+// encryptedAttributes are the attributes whose encoding depends on the
+// authenticator of the packet, which may change when it is re-encoded.
+var encryptedAttributes = map[string]bool{
+	"User-Password":         true,
+	"Tunnel-Password":       true,
+	"Message-Authenticator": true,
+	"MS-MPPE-Send-Key":      true,
+	"MS-MPPE-Recv-Key":      true,
+}
+
+func FuzzParse(f *testing.F) {
+	for _, payload := range pcapngPayloads(f, "radius.pcapng") {
+		f.Add(payload)
+	}
+
+	p := radius.New(radius.CodeAccessAccept, []byte("secret"))
+	p.Add("Service-Type", "Framed-User")
+	p.Add("Framed-IP-Address", net.IPv4(192, 0, 2, 1))
+	p.Add("Tunnel-Type", radius.Tagged{Tag: 1, Value: "VLAN"})
+	p.Add("MS-Primary-DNS-Server", net.IPv4(192, 0, 2, 53))
+	p.Add("Framed-IPv6-Prefix", &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(64, 128)})
+	p.Add("EAP-Message", make([]byte, 300))
+	if b, err := p.Encode(); err == nil {
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		secret := []byte("secret")
+		p, err := radius.Parse(data, secret, radius.Builtin)
+		lenient, lenientErr := radius.ParseLenient(data, secret, radius.Builtin)
+		if lenientErr == nil && (err == nil) != (len(lenient.Errors) == 0) || err == nil && lenientErr != nil {
+			t.Fatalf("Parse and ParseLenient disagree: %v, %v", err, lenientErr)
+		}
+		if err != nil {
+			return
+		}
+
+		// Parse(Encode(p)) must be stable once the packet has been
+		// normalized by a first round trip.
+		b, err := p.Encode()
+		if err != nil {
+			return
+		}
+		q, err := radius.Parse(b, secret, radius.Builtin)
+		if err != nil {
+			t.Fatalf("cannot parse encoded packet %x: %v", b, err)
+		}
+		b2, err := q.Encode()
+		if err != nil {
+			t.Fatalf("cannot encode parsed packet %x: %v", b, err)
+		}
+		r, err := radius.Parse(b2, secret, radius.Builtin)
+		if err != nil {
+			t.Fatalf("cannot parse encoded packet %x: %v", b2, err)
+		}
+		if len(q.Attributes) != len(r.Attributes) {
+			t.Fatalf("round trip changed the number of attributes: %x, %x", b, b2)
+		}
+		for i, attr := range q.Attributes {
+			if name, _ := radius.Builtin.AttrName(attr); encryptedAttributes[name] {
+				continue
+			}
+			if !reflect.DeepEqual(attr, r.Attributes[i]) {
+				t.Fatalf("round trip changed attribute %d: %#v, %#v", i, attr, r.Attributes[i])
+			}
+		}
+	})
+}
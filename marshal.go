@@ -0,0 +1,302 @@
+package radius
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Marshal adds the attributes described by the fields of the struct pointed
+// to by v to p. Fields are mapped to attributes with the "radius" struct tag,
+// which holds the attribute's name in p's Dictionary:
+//
+//	type Response struct {
+//		ReplyMessage []string `radius:"Reply-Message"`
+//		SessionTimeout *uint32 `radius:"Session-Timeout"`
+//		ServiceType string `radius:"Service-Type"`
+//	}
+//
+// A slice field adds an attribute for each element, and a pointer field adds
+// an attribute unless it is nil. Fields with the ",omitempty" option are
+// skipped if they hold their zero value. Fields without a tag, or with the tag
+// "-", are ignored; embedded structs are marshaled as if their fields were
+// part of v.
+//
+// Values are converted by the attribute's codec, as with Packet.Add, so the
+// names of enumerated values can be used. Before that, values of any integer
+// type are converted to the integer type of the codec, such as an int to the
+// uint32 of AttributeInteger, and values of named string types to string. An
+// error is returned if a value is out of range or is not accepted by the
+// codec; p is unchanged in that case.
+func Marshal(v interface{}, p *Packet) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("radius: Marshal requires a struct or a pointer to a struct")
+	}
+	var attrs []*Attribute
+	if err := marshalStruct(rv, p, &attrs); err != nil {
+		return err
+	}
+	p.Attributes = append(p.Attributes, attrs...)
+	return nil
+}
+
+// fieldTag parses the radius struct tag of a field. ok is false if the field
+// is not mapped to an attribute.
+func fieldTag(field reflect.StructField) (name string, omitEmpty, ok bool) {
+	tag := field.Tag.Get("radius")
+	if tag == "" || tag == "-" {
+		return
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, true
+}
+
+func marshalStruct(rv reflect.Value, p *Packet, attrs *[]*Attribute) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		value := rv.Field(i)
+		name, omitEmpty, ok := fieldTag(field)
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := marshalStruct(value, p, attrs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if omitEmpty && value.IsZero() {
+			continue
+		}
+
+		var values []reflect.Value
+		switch {
+		case value.Kind() == reflect.Ptr:
+			if value.IsNil() {
+				continue
+			}
+			values = append(values, value.Elem())
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8:
+			for j := 0; j < value.Len(); j++ {
+				values = append(values, value.Index(j))
+			}
+		default:
+			values = append(values, value)
+		}
+
+		entry := p.Dictionary.get(name)
+		if entry == nil {
+			return fmt.Errorf("radius: field %s: attribute name not registered", field.Name)
+		}
+		for _, value := range values {
+			converted, err := convertValue(value, entry.Codec)
+			if err != nil {
+				return fmt.Errorf("radius: field %s: %v", field.Name, err)
+			}
+			attr, err := newAttribute(entry, converted)
+			if err != nil {
+				return fmt.Errorf("radius: field %s: %v", field.Name, err)
+			}
+			// Check that the value can be encoded, so that type errors are
+			// reported here instead of by Encode.
+			if _, err := p.Dictionary.codec(attr).Encode(p, attr.Value); err != nil {
+				return fmt.Errorf("radius: field %s: %v", field.Name, err)
+			}
+			*attrs = append(*attrs, attr)
+		}
+	}
+	return nil
+}
+
+// integerType returns the type of the values of an integer codec, or nil if
+// codec is not one.
+func integerType(codec AttributeCodec) reflect.Type {
+	if enum, ok := codec.(*attributeEnum); ok {
+		codec = enum.AttributeCodec
+	}
+	switch codec {
+	case AttributeInteger, AttributeTaggedInteger:
+		return reflect.TypeOf(uint32(0))
+	case AttributeInteger64:
+		return reflect.TypeOf(uint64(0))
+	case AttributeByte:
+		return reflect.TypeOf(uint8(0))
+	case AttributeShort:
+		return reflect.TypeOf(uint16(0))
+	case AttributeSigned:
+		return reflect.TypeOf(int32(0))
+	}
+	return nil
+}
+
+// convertValue returns the value of a field to pass to codec, as described by
+// Marshal.
+func convertValue(value reflect.Value, codec AttributeCodec) (interface{}, error) {
+	if value.Kind() == reflect.String && value.Type() != typeString {
+		return value.String(), nil
+	}
+	t := integerType(codec)
+	if t == nil || !isInteger(value.Kind()) || value.Type() == t {
+		return value.Interface(), nil
+	}
+	if overflows(value, t) {
+		return nil, fmt.Errorf("%v does not fit in %s", value.Interface(), t)
+	}
+	return value.Convert(t).Interface(), nil
+}
+
+// overflows returns if the integer value cannot be represented by the
+// integer type t.
+func overflows(value reflect.Value, t reflect.Type) bool {
+	target := reflect.New(t).Elem()
+	unsigned := t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr
+	if value.Kind() >= reflect.Int && value.Kind() <= reflect.Int64 {
+		n := value.Int()
+		if unsigned {
+			return n < 0 || target.OverflowUint(uint64(n))
+		}
+		return target.OverflowInt(n)
+	}
+	n := value.Uint()
+	if unsigned {
+		return target.OverflowUint(n)
+	}
+	return n > math.MaxInt64 || target.OverflowInt(int64(n))
+}
+
+// Unmarshal stores the values of p's attributes in the fields of the struct
+// pointed to by v, using the struct tags described by Marshal. A slice field
+// is set to the values of every attribute with the name, in order, and other
+// fields to the value of the first one; pointer fields are allocated as needed.
+// Fields whose attribute is not in p are left unchanged. Invalid attributes
+// (see ParseLenient) are skipped.
+//
+// Values are stored if their type is assignable to the type of the field, and
+// the values of integer attributes in integer fields of any type that can hold
+// them. In addition, string fields receive the name of enumerated values
+// and the text of []byte values, []byte fields receive the bytes of string
+// values, and the values of tagged attributes are stored without their tag
+// unless the field is a Tagged. Otherwise an error is returned.
+func Unmarshal(p *Packet, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("radius: Unmarshal requires a non-nil pointer to a struct")
+	}
+	return unmarshalStruct(p, rv.Elem())
+}
+
+func unmarshalStruct(p *Packet, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		value := rv.Field(i)
+		name, _, ok := fieldTag(field)
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := unmarshalStruct(p, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		entry := p.Dictionary.get(name)
+		if entry == nil {
+			return fmt.Errorf("radius: field %s: attribute name not registered", field.Name)
+		}
+		var attrs []*Attribute
+		for _, attr := range p.Attributes {
+			if !attr.Invalid && attr.Vendor == entry.Vendor && attr.Type == entry.Type && attr.ExtendedType == entry.ExtendedType {
+				attrs = append(attrs, attr)
+			}
+		}
+		if len(attrs) == 0 {
+			continue
+		}
+
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+			values := reflect.MakeSlice(value.Type(), len(attrs), len(attrs))
+			for j, attr := range attrs {
+				if err := setField(values.Index(j), attr.Value, entry.Codec); err != nil {
+					return fmt.Errorf("radius: field %s: %v", field.Name, err)
+				}
+			}
+			value.Set(values)
+			continue
+		}
+		target := reflect.New(value.Type()).Elem()
+		if err := setField(target, attrs[0].Value, entry.Codec); err != nil {
+			return fmt.Errorf("radius: field %s: %v", field.Name, err)
+		}
+		value.Set(target)
+	}
+	return nil
+}
+
+var (
+	typeTagged = reflect.TypeOf(Tagged{})
+	typeBytes  = reflect.TypeOf([]byte(nil))
+	typeString = reflect.TypeOf("")
+)
+
+// setField stores value, which was decoded by codec, in target.
+func setField(target reflect.Value, value interface{}, codec AttributeCodec) error {
+	if target.Kind() == reflect.Ptr {
+		elem := reflect.New(target.Type().Elem())
+		if err := setField(elem.Elem(), value, codec); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	}
+
+	if tagged, ok := value.(Tagged); ok && target.Type() != typeTagged {
+		if stringer, ok := codec.(AttributeStringer); ok && target.Kind() == reflect.String {
+			target.SetString(stringer.String(tagged))
+			return nil
+		}
+		value = tagged.Value
+	}
+
+	rv := reflect.ValueOf(value)
+	switch {
+	case target.Kind() == reflect.String && rv.Kind() != reflect.String:
+		if stringer, ok := codec.(AttributeStringer); ok {
+			target.SetString(stringer.String(value))
+			return nil
+		}
+		if raw, ok := value.([]byte); ok {
+			target.SetString(string(raw))
+			return nil
+		}
+	case target.Type() == typeBytes && rv.Kind() == reflect.String:
+		target.SetBytes([]byte(rv.String()))
+		return nil
+	case rv.IsValid() && rv.Type().AssignableTo(target.Type()):
+		target.Set(rv)
+		return nil
+	case rv.IsValid() && integerType(codec) != nil && isInteger(rv.Kind()) && isInteger(target.Kind()):
+		if overflows(rv, target.Type()) {
+			return fmt.Errorf("%v does not fit in %s", value, target.Type())
+		}
+		target.Set(rv.Convert(target.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot store %T in %s", value, target.Type())
+}
+
+// isInteger returns if k is an integer kind.
+func isInteger(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uintptr
+}
@@ -0,0 +1,309 @@
+package radius_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/runner-mei/radius"
+)
+
+type accountingRequest struct {
+	UserName    string   `radius:"User-Name"`
+	StatusType  string   `radius:"Acct-Status-Type"`
+	SessionID   string   `radius:"Acct-Session-Id"`
+	SessionTime *uint32  `radius:"Acct-Session-Time"`
+	DelayTime   *uint32  `radius:"Acct-Delay-Time"`
+	FramedIP    net.IP   `radius:"Framed-IP-Address"`
+	Class       [][]byte `radius:"Class"`
+	DNS         net.IP   `radius:"MS-Primary-DNS-Server,omitempty"`
+	TunnelType  uint32   `radius:"Tunnel-Type,omitempty"`
+	Ignored     string
+}
+
+func Test_MarshalUnmarshal(t *testing.T) {
+	sessionTime := uint32(3600)
+	req := accountingRequest{
+		UserName:    "nemo",
+		StatusType:  "Stop",
+		SessionID:   "0001",
+		SessionTime: &sessionTime,
+		FramedIP:    net.IPv4(192, 0, 2, 1).To4(),
+		Class:       [][]byte{[]byte("a"), []byte("b")},
+		DNS:         net.IPv4(192, 0, 2, 53).To4(),
+		Ignored:     "x",
+	}
+
+	p := radius.New(radius.CodeAccountingRequest, []byte("secret"))
+	if err := radius.Marshal(&req, p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Attributes) != 8 {
+		t.Fatalf("expecting 8 attributes, got %d", len(p.Attributes))
+	}
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := radius.Parse(b, []byte("secret"), radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded accountingRequest
+	if err := radius.Unmarshal(q, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	req.Ignored = ""
+	if !reflect.DeepEqual(req, decoded) {
+		t.Fatalf("expecting %+v, got %+v", req, decoded)
+	}
+
+	var wrongType struct {
+		Status string `radius:"Acct-Session-Time"`
+	}
+	wrongType.Status = "x"
+	if err := radius.Marshal(&wrongType, p); err == nil {
+		t.Fatal("expecting Marshal to reject a string for an integer attribute")
+	}
+	var wrongField struct {
+		Time net.IP `radius:"Acct-Session-Time"`
+	}
+	if err := radius.Unmarshal(q, &wrongField); err == nil {
+		t.Fatal("expecting Unmarshal to reject an integer for a net.IP field")
+	}
+}
+
+func Test_MarshalIntegers(t *testing.T) {
+	type serviceType uint16
+	var response struct {
+		SessionTimeout int         `radius:"Session-Timeout"`
+		IdleTimeout    *int64      `radius:"Idle-Timeout"`
+		ServiceType    serviceType `radius:"Service-Type"`
+	}
+	idleTimeout := int64(600)
+	response.SessionTimeout = 3600
+	response.IdleTimeout = &idleTimeout
+	response.ServiceType = 2
+
+	p := radius.New(radius.CodeAccessAccept, []byte("secret"))
+	if err := radius.Marshal(&response, p); err != nil {
+		t.Fatal(err)
+	}
+	if v := p.Value("Session-Timeout"); v != uint32(3600) {
+		t.Fatalf("expecting Session-Timeout 3600, got %#v", v)
+	}
+	if v := p.Value("Idle-Timeout"); v != uint32(600) {
+		t.Fatalf("expecting Idle-Timeout 600, got %#v", v)
+	}
+	if v := p.Value("Service-Type"); v != uint32(2) {
+		t.Fatalf("expecting Service-Type 2, got %#v", v)
+	}
+
+	for _, timeout := range []int{-1, 1 << 32} {
+		response.SessionTimeout = timeout
+		q := radius.New(radius.CodeAccessAccept, []byte("secret"))
+		if err := radius.Marshal(&response, q); err == nil {
+			t.Fatalf("expecting Marshal to reject Session-Timeout %d", timeout)
+		}
+		if len(q.Attributes) != 0 {
+			t.Fatalf("expecting no attributes after an error, got %d", len(q.Attributes))
+		}
+	}
+}
+
+func Test_MarshalEnumNames(t *testing.T) {
+	type tunnel struct {
+		Type       string `radius:"Tunnel-Type"`
+		MediumType uint32 `radius:"Tunnel-Medium-Type"`
+	}
+	type status string
+	var request struct {
+		ServiceType string `radius:"Service-Type"`
+		StatusType  status `radius:"Acct-Status-Type"`
+		tunnel
+	}
+	request.ServiceType = "Framed-User"
+	request.StatusType = "Start"
+	request.Type = "VLAN"
+	request.MediumType = 6
+
+	p := radius.New(radius.CodeAccountingRequest, []byte("secret"))
+	if err := radius.Marshal(&request, p); err != nil {
+		t.Fatal(err)
+	}
+	if v := p.Value("Service-Type"); v != uint32(2) {
+		t.Fatalf("expecting Service-Type 2, got %#v", v)
+	}
+	if v := p.Value("Acct-Status-Type"); v != uint32(1) {
+		t.Fatalf("expecting Acct-Status-Type 1, got %#v", v)
+	}
+
+	var names struct {
+		ServiceType string `radius:"Service-Type"`
+		TunnelType  string `radius:"Tunnel-Type"`
+		MediumType  string `radius:"Tunnel-Medium-Type"`
+	}
+	if err := radius.Unmarshal(p, &names); err != nil {
+		t.Fatal(err)
+	}
+	if names.ServiceType != "Framed-User" || names.TunnelType != "VLAN" || names.MediumType != "IEEE-802" {
+		t.Fatalf("unexpected names %+v", names)
+	}
+	var numbers struct {
+		ServiceType int   `radius:"Service-Type"`
+		TunnelType  uint8 `radius:"Tunnel-Type"`
+	}
+	if err := radius.Unmarshal(p, &numbers); err != nil {
+		t.Fatal(err)
+	}
+	if numbers.ServiceType != 2 || numbers.TunnelType != 13 {
+		t.Fatalf("unexpected numbers %+v", numbers)
+	}
+
+	request.ServiceType = "No-Such-Service"
+	if err := radius.Marshal(&request, p); err == nil {
+		t.Fatal("expecting Marshal to reject an unknown value name")
+	}
+}
+
+func Test_MarshalVendorSpecific(t *testing.T) {
+	type dns struct {
+		Primary   net.IP `radius:"MS-Primary-DNS-Server"`
+		Secondary net.IP `radius:"MS-Secondary-DNS-Server,omitempty"`
+	}
+	request := dns{Primary: net.IPv4(192, 0, 2, 53).To4()}
+
+	p := radius.New(radius.CodeAccessAccept, []byte("secret"))
+	if err := radius.Marshal(&request, p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Attributes) != 1 || p.Attributes[0].Vendor != radius.VendorMicrosoft {
+		t.Fatalf("expecting one Microsoft attribute, got %+v", p.Attributes)
+	}
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Vendor-Specific, Microsoft, MS-Primary-DNS-Server
+	if expected := []byte{26, 12, 0, 0, 1, 55, 28, 6, 192, 0, 2, 53}; !reflect.DeepEqual(b[20:], expected) {
+		t.Fatalf("expecting %v, got %v", expected, b[20:])
+	}
+
+	q, err := radius.Parse(b, []byte("secret"), radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded dns
+	if err := radius.Unmarshal(q, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(request, decoded) {
+		t.Fatalf("expecting %+v, got %+v", request, decoded)
+	}
+}
+
+func Test_MarshalPointersAndSlices(t *testing.T) {
+	type response struct {
+		Messages       []string `radius:"Reply-Message"`
+		SessionTimeout *uint32  `radius:"Session-Timeout"`
+		IdleTimeout    *uint32  `radius:"Idle-Timeout"`
+		Filters        []string `radius:"Filter-Id"`
+	}
+	sessionTime := uint32(60)
+	accept := response{
+		Messages:       []string{"hello", "world"},
+		SessionTimeout: &sessionTime,
+	}
+
+	p := radius.New(radius.CodeAccessAccept, []byte("secret"))
+	if err := radius.Marshal(accept, p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Attributes) != 3 {
+		t.Fatalf("expecting 3 attributes, got %d", len(p.Attributes))
+	}
+
+	decoded := response{Filters: []string{"existing"}}
+	if err := radius.Unmarshal(p, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Messages, accept.Messages) {
+		t.Fatalf("expecting messages %q, got %q", accept.Messages, decoded.Messages)
+	}
+	if decoded.SessionTimeout == nil || *decoded.SessionTimeout != sessionTime {
+		t.Fatalf("expecting Session-Timeout %d, got %v", sessionTime, decoded.SessionTimeout)
+	}
+	if decoded.IdleTimeout != nil {
+		t.Fatalf("expecting no Idle-Timeout, got %d", *decoded.IdleTimeout)
+	}
+	if !reflect.DeepEqual(decoded.Filters, []string{"existing"}) {
+		t.Fatalf("expecting Filter-Id to be unchanged, got %q", decoded.Filters)
+	}
+
+	// Slices are replaced, not appended to, when a struct is reused.
+	if err := radius.Unmarshal(p, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Messages, accept.Messages) {
+		t.Fatalf("expecting messages %q after a second Unmarshal, got %q", accept.Messages, decoded.Messages)
+	}
+}
+
+func Test_MarshalErrors(t *testing.T) {
+	p := radius.New(radius.CodeAccessRequest, []byte("secret"))
+	p.Add("User-Name", "nemo")
+
+	var unknown struct {
+		Value string `radius:"No-Such-Attribute"`
+	}
+	if err := radius.Marshal(&unknown, p); err == nil {
+		t.Fatal("expecting Marshal to reject an unregistered name")
+	}
+	if err := radius.Unmarshal(p, &unknown); err == nil {
+		t.Fatal("expecting Unmarshal to reject an unregistered name")
+	}
+	if err := radius.Marshal("nemo", p); err == nil {
+		t.Fatal("expecting Marshal to reject a string")
+	}
+
+	var request struct {
+		UserName string `radius:"User-Name"`
+	}
+	if err := radius.Unmarshal(p, request); err == nil {
+		t.Fatal("expecting Unmarshal to reject a struct that is not a pointer")
+	}
+	var nilRequest *struct {
+		UserName string `radius:"User-Name"`
+	}
+	if err := radius.Unmarshal(p, nilRequest); err == nil {
+		t.Fatal("expecting Unmarshal to reject a nil pointer")
+	}
+
+	p.Add("Session-Timeout", uint32(300))
+	var small struct {
+		SessionTimeout int8 `radius:"Session-Timeout"`
+	}
+	if err := radius.Unmarshal(p, &small); err == nil {
+		t.Fatalf("expecting Unmarshal to reject 300 for an int8 field, got %d", small.SessionTimeout)
+	}
+	var float struct {
+		SessionTimeout float64 `radius:"Session-Timeout"`
+	}
+	if err := radius.Unmarshal(p, &float); err == nil {
+		t.Fatal("expecting Unmarshal to reject an integer for a float64 field")
+	}
+
+	var partial struct {
+		UserName    string `radius:"User-Name"`
+		SessionTime string `radius:"Session-Timeout"`
+	}
+	partial.UserName = "x"
+	partial.SessionTime = "forever"
+	if err := radius.Marshal(&partial, p); err == nil {
+		t.Fatal("expecting Marshal to reject a string for an integer attribute")
+	}
+	if len(p.Attributes) != 2 {
+		t.Fatalf("expecting p to be unchanged, got %d attributes", len(p.Attributes))
+	}
+}
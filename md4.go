@@ -0,0 +1,69 @@
+package radius
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// md4Sum returns the MD4 checksum of data (RFC 1320). MD4 is only used for
+// the NT password hash of MS-CHAP and must not be used for anything else.
+func md4Sum(data []byte) [16]byte {
+	length := uint64(len(data)) << 3
+
+	msg := make([]byte, len(data), len(data)+72)
+	copy(msg, data)
+	msg = append(msg, 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], length)
+	msg = append(msg, size[:]...)
+
+	a, b, c, d := uint32(0x67452301), uint32(0xefcdab89), uint32(0x98badcfe), uint32(0x10325476)
+
+	var x [16]uint32
+	for len(msg) > 0 {
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(msg[i*4:])
+		}
+		aa, bb, cc, dd := a, b, c, d
+
+		// Round 1
+		for _, i := range [...]uint{0, 4, 8, 12} {
+			a = bits.RotateLeft32(a+((b&c)|(^b&d))+x[i], 3)
+			d = bits.RotateLeft32(d+((a&b)|(^a&c))+x[i+1], 7)
+			c = bits.RotateLeft32(c+((d&a)|(^d&b))+x[i+2], 11)
+			b = bits.RotateLeft32(b+((c&d)|(^c&a))+x[i+3], 19)
+		}
+
+		// Round 2
+		for _, i := range [...]uint{0, 1, 2, 3} {
+			a = bits.RotateLeft32(a+((b&c)|(b&d)|(c&d))+x[i]+0x5a827999, 3)
+			d = bits.RotateLeft32(d+((a&b)|(a&c)|(b&c))+x[i+4]+0x5a827999, 5)
+			c = bits.RotateLeft32(c+((d&a)|(d&b)|(a&b))+x[i+8]+0x5a827999, 9)
+			b = bits.RotateLeft32(b+((c&d)|(c&a)|(d&a))+x[i+12]+0x5a827999, 13)
+		}
+
+		// Round 3
+		for _, i := range [...]uint{0, 2, 1, 3} {
+			a = bits.RotateLeft32(a+(b^c^d)+x[i]+0x6ed9eba1, 3)
+			d = bits.RotateLeft32(d+(a^b^c)+x[i+8]+0x6ed9eba1, 9)
+			c = bits.RotateLeft32(c+(d^a^b)+x[i+4]+0x6ed9eba1, 11)
+			b = bits.RotateLeft32(b+(c^d^a)+x[i+12]+0x6ed9eba1, 15)
+		}
+
+		a += aa
+		b += bb
+		c += cc
+		d += dd
+		msg = msg[64:]
+	}
+
+	var sum [16]byte
+	binary.LittleEndian.PutUint32(sum[0:], a)
+	binary.LittleEndian.PutUint32(sum[4:], b)
+	binary.LittleEndian.PutUint32(sum[8:], c)
+	binary.LittleEndian.PutUint32(sum[12:], d)
+	return sum
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/binary"
 	"errors"
+	"reflect"
 	"strconv"
 )
 
@@ -28,6 +30,64 @@ const (
 	CodeReserved           Code = 255
 )
 
+// Codes which are defined in RFC 5176 (Dynamic Authorization).
+const (
+	CodeDisconnectRequest Code = 40
+	CodeDisconnectACK     Code = 41
+	CodeDisconnectNAK     Code = 42
+	CodeCoARequest        Code = 43
+	CodeCoAACK            Code = 44
+	CodeCoANAK            Code = 45
+)
+
+var codeNames = map[Code]string{
+	CodeAccessRequest:      "Access-Request",
+	CodeAccessAccept:       "Access-Accept",
+	CodeAccessReject:       "Access-Reject",
+	CodeAccountingRequest:  "Accounting-Request",
+	CodeAccountingResponse: "Accounting-Response",
+	CodeAccessChallenge:    "Access-Challenge",
+	CodeStatusServer:       "Status-Server",
+	CodeStatusClient:       "Status-Client",
+	CodeReserved:           "Reserved",
+	CodeDisconnectRequest:  "Disconnect-Request",
+	CodeDisconnectACK:      "Disconnect-ACK",
+	CodeDisconnectNAK:      "Disconnect-NAK",
+	CodeCoARequest:         "CoA-Request",
+	CodeCoAACK:             "CoA-ACK",
+	CodeCoANAK:             "CoA-NAK",
+}
+
+// hasZeroAuthenticator returns if the Request Authenticator of packets with
+// the code is calculated over 16 zero bytes in place of the authenticator, as
+// for Accounting-Request packets (RFC 2866 section 3).
+func (c Code) hasZeroAuthenticator() bool {
+	switch c {
+	case CodeAccountingRequest, CodeDisconnectRequest, CodeCoARequest:
+		return true
+	}
+	return false
+}
+
+// isResponse returns if the Response Authenticator of packets with the code
+// is calculated over the authenticator of the request.
+func (c Code) isResponse() bool {
+	switch c {
+	case CodeAccessAccept, CodeAccessReject, CodeAccessChallenge, CodeAccountingResponse,
+		CodeDisconnectACK, CodeDisconnectNAK, CodeCoAACK, CodeCoANAK:
+		return true
+	}
+	return false
+}
+
+// String returns the name of the code, such as "Access-Request".
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return "Code(" + strconv.Itoa(int(c)) + ")"
+}
+
 // Packet defines a RADIUS packet.
 type Packet struct {
 	Code          Code
@@ -39,6 +99,106 @@ type Packet struct {
 	Dictionary *Dictionary
 
 	Attributes []*Attribute
+
+	// Errors holds the errors of the attributes which could not be decoded
+	// by ParseLenient. Each of the attributes is marked as Invalid.
+	Errors []*AttributeError
+
+	// wire data the packet was parsed from, if any
+	raw []byte
+	// the attributes as they were parsed, which are encoded unchanged if
+	// they are not modified
+	parsed map[*Attribute]*parsedAttribute
+	// the packet whose authenticator was used to decode the attributes,
+	// if it is not the packet itself
+	decoder *Packet
+}
+
+// wireSpan is the wire data of one or more consecutive attributes of a
+// parsed packet, such as a Vendor-Specific attribute with several
+// sub-attributes, or the fragments of a concat attribute.
+type wireSpan struct {
+	data  []byte
+	attrs []*Attribute
+}
+
+// parsedAttribute is an attribute as it was parsed.
+type parsedAttribute struct {
+	span         *wireSpan
+	vendor       uint32
+	t            byte
+	extendedType byte
+	invalid      bool
+	// the value that was decoded
+	value []byte
+}
+
+// unchanged returns if attr, which was parsed as a, still has the value it
+// was parsed with. Message-Authenticator attributes are never unchanged, as
+// they are calculated when encoding.
+func (a *parsedAttribute) unchanged(p *Packet, attr *Attribute) bool {
+	if attr.Vendor != a.vendor || attr.Type != a.t || attr.ExtendedType != a.extendedType || attr.Invalid != a.invalid {
+		return false
+	}
+	if attr.Invalid {
+		raw, ok := attr.Value.([]byte)
+		return ok && bytes.Equal(raw, a.value)
+	}
+	if attr.Vendor == 0 && attr.Type == attrMessageAuthenticator {
+		return false
+	}
+	// Decoding the value again also detects changes that affect encrypted
+	// values, such as a new secret or authenticator.
+	decoder := p.decoder
+	if decoder == nil {
+		decoder = p
+	}
+	decoded, err := p.Dictionary.codec(attr).Decode(decoder, a.value)
+	return err == nil && reflect.DeepEqual(decoded, attr.Value)
+}
+
+// unchangedSpan returns the wire span of the parsed attributes which start at
+// p.Attributes[i], if all of them follow in order and are unchanged. nil is
+// returned otherwise.
+func (p *Packet) unchangedSpan(i int) *wireSpan {
+	parsed := p.parsed[p.Attributes[i]]
+	if parsed == nil {
+		return nil
+	}
+	span := parsed.span
+	if span.attrs[0] != p.Attributes[i] || i+len(span.attrs) > len(p.Attributes) {
+		return nil
+	}
+	for k, attr := range span.attrs {
+		if p.Attributes[i+k] != attr || !p.parsed[attr].unchanged(p, attr) {
+			return nil
+		}
+	}
+	return span
+}
+
+// AttributeError is the error of an attribute whose value could not be
+// decoded.
+type AttributeError struct {
+	Attribute *Attribute
+	Err       error
+}
+
+func (e *AttributeError) Error() string {
+	return "radius: attribute " + attributeID(e.Attribute) + ": " + e.Err.Error()
+}
+
+// attributeID returns the type of attr, including its vendor and extended
+// type, as text.
+func attributeID(attr *Attribute) string {
+	id := strconv.Itoa(int(attr.Type))
+	if isExtendedType(attr.Type) {
+		id += "." + strconv.Itoa(int(attr.ExtendedType))
+	}
+	if attr.Vendor != 0 {
+		id = "26." + strconv.FormatUint(uint64(attr.Vendor), 10) + "." + id
+	}
+	return id
 }
 
 // New returns a new packet with the given code and secret. The identifier and
@@ -68,6 +228,39 @@ func New(code Code, secret []byte) *Packet {
 // Ensuring a packet's authenticity should be done using the IsAuthentic
 // method.
 func Parse(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
+	return parse(data, secret, dictionary, nil, false)
+}
+
+// ParseLenient is like Parse, except that attributes whose value cannot be
+// decoded, such as an integer attribute that is not 4 bytes long, do not
+// cause an error. They are kept with Invalid set and their wire value as
+// Value, and their errors are collected in the packet's Errors. Encoding the
+// packet writes the attributes that are not modified, invalid or not, as they
+// were received, so that an unmodified packet is encoded to its original wire
+// data.
+//
+// An error is still returned if the packet or the layout of its attributes is
+// malformed.
+func ParseLenient(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
+	return parse(data, secret, dictionary, nil, true)
+}
+
+// ParseResponse parses a RADIUS packet that is a response to request from
+// wire data, using request's shared secret and dictionary. Unlike Parse,
+// attributes that are encrypted using the Request Authenticator (such as
+// MS-MPPE-Recv-Key) are decoded using request's authenticator.
+//
+// Note: this function does not validate the authenticity of a packet.
+// Ensuring a packet's authenticity should be done using the IsAuthentic
+// method.
+func ParseResponse(data []byte, request *Packet) (*Packet, error) {
+	return parse(data, request.Secret, request.Dictionary, &request.Authenticator, false)
+}
+
+// parse implements Parse. If requestAuthenticator is not nil, attributes are
+// decoded as if the packet's authenticator were requestAuthenticator, which
+// is how responses are encoded. lenient is set for ParseLenient.
+func parse(data, secret []byte, dictionary *Dictionary, requestAuthenticator *[16]byte, lenient bool) (*Packet, error) {
 	if len(data) < 20 {
 		return nil, errors.New("radius: packet must be at least 20 bytes long")
 	}
@@ -79,77 +272,284 @@ func Parse(data, secret []byte, dictionary *Dictionary) (*Packet, error) {
 		Dictionary: dictionary,
 	}
 
-	length := binary.BigEndian.Uint16(data[2:4])
-	if length < 20 || length > maxPacketSize {
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < 20 || length > maxPacketSize || length > len(data) {
 		return nil, errors.New("radius: invalid packet length")
 	}
+	// Octets outside the range of the Length field are padding, which is
+	// ignored (RFC 2865 section 3).
+	data = data[:length]
 
 	copy(packet.Authenticator[:], data[4:20])
+	packet.raw = make([]byte, len(data))
+	copy(packet.raw, data)
+
+	decoder := packet
+	if requestAuthenticator != nil {
+		decoder = &Packet{
+			Code:          packet.Code,
+			Identifier:    packet.Identifier,
+			Authenticator: *requestAuthenticator,
+			Secret:        secret,
+			Dictionary:    dictionary,
+		}
+		packet.decoder = decoder
+	}
 
-	// Attributes
-	attributes := data[20:]
+	// Attributes are parsed from the copy of the data, which the spans of
+	// the attributes refer to.
+	var raws []rawAttribute
+	attributes := packet.raw[20:]
 	for len(attributes) > 0 {
 		if len(attributes) < 2 {
 			return nil, errors.New("radius: attribute must be at least 2 bytes long")
 		}
 
 		attrLength := attributes[1]
-		if attrLength < 1 || attrLength > 253 || len(attributes) < int(attrLength) {
+		if attrLength < 2 || len(attributes) < int(attrLength) {
 			return nil, errors.New("radius: invalid attribute length")
 		}
 		attrType := attributes[0]
 		attrValue := attributes[2:attrLength]
+		next := attributes[attrLength:]
+
+		if attrType == 26 {
+			if vsas := parseVendorSpecific(dictionary, attrValue); vsas != nil {
+				span := &wireSpan{data: attributes[:attrLength]}
+				for _, vsa := range vsas {
+					vsa.span = span
+					raws = append(raws, vsa)
+				}
+				attributes = next
+				continue
+			}
+		}
+
+		attr := &Attribute{
+			Type: attrType,
+		}
+		if isExtendedType(attrType) {
+			var err error
+			attrValue, next, err = parseExtended(attr, attrValue, next)
+			if err != nil {
+				return nil, err
+			}
+		}
+		span := &wireSpan{data: attributes[:len(attributes)-len(next)]}
+		raws = append(raws, rawAttribute{attr: attr, value: attrValue, span: span})
+		attributes = next
+	}
 
-		codec := dictionary.Codec(attrType)
-		decoded, err := codec.Decode(packet, attrValue)
+	packet.parsed = make(map[*Attribute]*parsedAttribute, len(raws))
+	for _, raw := range concatAttributes(dictionary, raws) {
+		decoded, err := dictionary.codec(raw.attr).Decode(decoder, raw.value)
 		if err != nil {
-			return nil, err
+			if !lenient {
+				return nil, err
+			}
+			value := make([]byte, len(raw.value))
+			copy(value, raw.value)
+			decoded = value
+			raw.attr.Invalid = true
+			packet.Errors = append(packet.Errors, &AttributeError{
+				Attribute: raw.attr,
+				Err:       err,
+			})
+		}
+		raw.attr.Value = decoded
+		packet.Attributes = append(packet.Attributes, raw.attr)
+
+		raw.span.attrs = append(raw.span.attrs, raw.attr)
+		packet.parsed[raw.attr] = &parsedAttribute{
+			span:         raw.span,
+			vendor:       raw.attr.Vendor,
+			t:            raw.attr.Type,
+			extendedType: raw.attr.ExtendedType,
+			invalid:      raw.attr.Invalid,
+			value:        raw.value,
+		}
+	}
+
+	return packet, nil
+}
+
+// rawAttribute is an attribute whose value has not been decoded yet.
+type rawAttribute struct {
+	attr  *Attribute
+	value []byte
+	// the wire data the attribute was parsed from
+	span *wireSpan
+}
+
+// concatAttributes joins the values of consecutive attributes of the same type
+// whose dictionary entry has the concat flag set.
+func concatAttributes(dictionary *Dictionary, raws []rawAttribute) []rawAttribute {
+	var joined []rawAttribute
+	for i := 0; i < len(raws); i++ {
+		raw := raws[i]
+		entry := dictionary.lookup(raw.attr)
+		if entry != nil && entry.Concat {
+			for i+1 < len(raws) && raws[i+1].attr.Vendor == raw.attr.Vendor &&
+				raws[i+1].attr.Type == raw.attr.Type && raws[i+1].attr.ExtendedType == raw.attr.ExtendedType {
+				i++
+				value := make([]byte, 0, len(raw.value)+len(raws[i].value))
+				value = append(value, raw.value...)
+				raw.value = append(value, raws[i].value...)
+				if span := raws[i].span; span != raw.span {
+					// The spans are adjacent in the packet: the joined
+					// attribute spans both of them.
+					raw.span.data = raw.span.data[:len(raw.span.data)+len(span.data)]
+					for j := i; j < len(raws) && raws[j].span == span; j++ {
+						raws[j].span = raw.span
+					}
+				}
+			}
+		}
+		joined = append(joined, raw)
+	}
+	return joined
+}
+
+// parseVendorSpecific splits the value of a Vendor-Specific attribute into
+// its sub-attributes, as recommended by RFC 2865 section 5.26. nil is returned
+// if the vendor is not registered in the dictionary or if the value does not
+// follow the vendor's format, in which case the attribute should be kept as
+// is.
+func parseVendorSpecific(dictionary *Dictionary, value []byte) []rawAttribute {
+	if len(value) < 4 {
+		return nil
+	}
+	vendor := binary.BigEndian.Uint32(value[0:4])
+	if vendor == 0 || !dictionary.hasVendor(vendor) {
+		return nil
+	}
+
+	format := dictionary.vendorFormat(vendor)
+
+	var raws []rawAttribute
+	for data := value[4:]; len(data) > 0; {
+		t, length, ok := format.parseHeader(data)
+		if !ok {
+			return nil
 		}
 		attr := &Attribute{
-			Type:  attrType,
-			Value: decoded,
+			Vendor: vendor,
+			Type:   t,
 		}
-		packet.Attributes = append(packet.Attributes, attr)
-		attributes = attributes[attrLength:]
+		raws = append(raws, rawAttribute{attr: attr, value: data[format.headerSize():length]})
+		data = data[length:]
 	}
+	return raws
+}
 
-	// TODO: validate that the given packet (by code) has all the required attributes, etc.
+// parseHeader parses the header of the vendor sub-attribute at the start of
+// data, returning its type and its length including the header. ok is false
+// if the header is invalid or the type does not fit in Attribute.Type.
+func (f vendorFormat) parseHeader(data []byte) (t byte, length int, ok bool) {
+	size := f.headerSize()
+	if len(data) < size {
+		return
+	}
+	var vendorType uint32
+	for _, b := range data[:f.TypeSize] {
+		vendorType = vendorType<<8 | uint32(b)
+	}
+	if vendorType > 255 {
+		return
+	}
+	switch f.LengthSize {
+	case 0:
+		length = len(data)
+	case 1:
+		length = int(data[f.TypeSize])
+	case 2:
+		length = int(binary.BigEndian.Uint16(data[f.TypeSize:]))
+	}
+	if length < size || length > len(data) {
+		return
+	}
+	return byte(vendorType), length, true
+}
 
-	return packet, nil
+// appendHeader appends the header of a vendor sub-attribute of the given type
+// whose value is n bytes long to buf.
+func (f vendorFormat) appendHeader(buf *bytes.Buffer, t byte, n int) {
+	for i := 1; i < f.TypeSize; i++ {
+		buf.WriteByte(0)
+	}
+	buf.WriteByte(t)
+	length := n + f.headerSize()
+	switch f.LengthSize {
+	case 1:
+		buf.WriteByte(byte(length))
+	case 2:
+		buf.WriteByte(byte(length >> 8))
+		buf.WriteByte(byte(length))
+	}
+	if f.Continuation {
+		buf.WriteByte(0)
+	}
 }
 
 // IsAuthentic returns if the packet is an authenticate response to the given
-// request packet. Calling this function is only valid if both:
+// request packet. If p was returned by Parse, the packet is verified as it was
+// received; otherwise it is encoded first. Calling this function is only valid
+// if both:
 //  - p.code is one of:
 //      CodeAccessAccept
 //      CodeAccessReject
 //      CodeAccountingRequest
 //      CodeAccountingResponse
 //      CodeAccessChallenge
+//      CodeDisconnectRequest
+//      CodeDisconnectACK
+//      CodeDisconnectNAK
+//      CodeCoARequest
+//      CodeCoAACK
+//      CodeCoANAK
 //  - p.Authenticator contains the calculated authenticator
+//
+// The Request Authenticator of Accounting-Request, Disconnect-Request and
+// CoA-Request packets does not depend on another packet; request only
+// provides the secret, and can be p itself.
 func (p *Packet) IsAuthentic(request *Packet) bool {
-	switch p.Code {
-	case CodeAccessAccept, CodeAccessReject, CodeAccountingRequest, CodeAccessChallenge:
-		wire, err := p.Encode()
+	if !p.Code.isResponse() && !p.Code.hasZeroAuthenticator() {
+		return false
+	}
+
+	var attrs []byte
+	if p.raw != nil {
+		attrs = p.raw[20:]
+	} else {
+		var msgAuth int
+		var err error
+		attrs, msgAuth, err = p.encodeAttributes()
 		if err != nil {
 			return false
 		}
-
-		hash := md5.New()
-		hash.Write(wire[0:4])
-		if p.Code == CodeAccountingRequest {
-			var nul [16]byte
-			hash.Write(nul[:])
-		} else {
-			hash.Write(request.Authenticator[:])
+		if msgAuth >= 0 {
+			// The authenticator covers the Message-Authenticator as it
+			// was received.
+			if value, ok := p.messageAuthenticatorAttr().Value.([]byte); ok {
+				copy(attrs[msgAuth:msgAuth+md5.Size], value)
+			}
 		}
-		hash.Write(wire[20:])
-		hash.Write(request.Secret)
+	}
+	length := 20 + len(attrs)
 
-		var sum [md5.Size]byte
-		return bytes.Equal(hash.Sum(sum[0:0]), p.Authenticator[:])
+	hash := md5.New()
+	hash.Write([]byte{byte(p.Code), p.Identifier, byte(length >> 8), byte(length)})
+	if p.Code.hasZeroAuthenticator() {
+		var nul [16]byte
+		hash.Write(nul[:])
+	} else {
+		hash.Write(request.Authenticator[:])
 	}
-	return false
+	hash.Write(attrs)
+	hash.Write(request.Secret)
+
+	var sum [md5.Size]byte
+	return bytes.Equal(hash.Sum(sum[0:0]), p.Authenticator[:])
 }
 
 // ClearAttributes removes all of the packet's attributes.
@@ -170,7 +570,7 @@ func (p *Packet) Value(name string) interface{} {
 // name. nil is returned if no such attribute exists.
 func (p *Packet) Attr(name string) *Attribute {
 	for _, attr := range p.Attributes {
-		if attrName, ok := p.Dictionary.Name(attr.Type); ok && attrName == name {
+		if attrName, ok := p.Dictionary.AttrName(attr); ok && attrName == name {
 			return attr
 		}
 	}
@@ -196,7 +596,7 @@ func (p *Packet) String(name string) string {
 	}
 	value := attr.Value
 
-	if codec := p.Dictionary.Codec(attr.Type); codec != nil {
+	if codec := p.Dictionary.codec(attr); codec != nil && !attr.Invalid {
 		if stringer, ok := codec.(AttributeStringer); ok {
 			return stringer.String(value)
 		}
@@ -236,8 +636,8 @@ func (p *Packet) AddAttr(attribute *Attribute) {
 // given name. If no such attribute exists, a new attribute is added
 func (p *Packet) Set(name string, value interface{}) error {
 	for _, attr := range p.Attributes {
-		if attrName, ok := p.Dictionary.Name(attr.Type); ok && attrName == name {
-			codec := p.Dictionary.Codec(attr.Type)
+		if attrName, ok := p.Dictionary.AttrName(attr); ok && attrName == name {
+			codec := p.Dictionary.codec(attr)
 			if transformer, ok := codec.(AttributeTransformer); ok {
 				transformed, err := transformer.Transform(value)
 				if err != nil {
@@ -285,11 +685,64 @@ func (p *Packet) PAP() (username, password string, ok bool) {
 	return
 }
 
+// CHAP returns the User-Name and the CHAP-Password and CHAP-Challenge
+// attributes of an Access-Request packet. ident and response are the CHAP
+// identifier and response contained in CHAP-Password. If the packet has no
+// CHAP-Challenge attribute, challenge is the packet's Request Authenticator
+// (RFC 2865 section 2.2).
+//
+// If packet's code is Access-Request, and the packet has a User-Name and a
+// valid CHAP-Password attribute, ok is true. Otherwise, it is false.
+func (p *Packet) CHAP() (username string, ident byte, response, challenge []byte, ok bool) {
+	if p.Code != CodeAccessRequest {
+		return
+	}
+	userStr, valid := p.Value("User-Name").(string)
+	if !valid {
+		return
+	}
+	chapPassword, valid := p.Value("CHAP-Password").([]byte)
+	if !valid || len(chapPassword) != 1+md5.Size {
+		return
+	}
+	if chapChallenge, valid := p.Value("CHAP-Challenge").([]byte); valid {
+		challenge = chapChallenge
+	} else {
+		challenge = p.Authenticator[:]
+	}
+	username = userStr
+	ident = chapPassword[0]
+	response = chapPassword[1:]
+	ok = true
+	return
+}
+
+// VerifyCHAP returns if the packet's CHAP-Password attribute is the correct
+// response for the given cleartext password, as described in RFC 1994.
+// false is returned if the packet does not contain CHAP credentials.
+func (p *Packet) VerifyCHAP(password string) bool {
+	_, ident, response, challenge, ok := p.CHAP()
+	if !ok {
+		return false
+	}
+	hash := md5.New()
+	hash.Write([]byte{ident})
+	hash.Write([]byte(password))
+	hash.Write(challenge)
+
+	var sum [md5.Size]byte
+	return subtle.ConstantTimeCompare(hash.Sum(sum[0:0]), response) == 1
+}
+
 func (p *Packet) GetAttributes() map[string]interface{} {
 	var results = map[string]interface{}{}
 	for _, attr := range p.Attributes {
-		if name, ok := p.Dictionary.Name(attr.Type); ok {
+		if name, ok := p.Dictionary.AttrName(attr); ok {
 			results[name] = attr.Value
+		} else if isExtendedType(attr.Type) {
+			results["unknown_"+strconv.FormatInt(int64(attr.Type), 10)+"."+strconv.FormatInt(int64(attr.ExtendedType), 10)] = attr.Value
+		} else if attr.Vendor != 0 {
+			results["unknown_"+strconv.FormatUint(uint64(attr.Vendor), 10)+"_"+strconv.FormatInt(int64(attr.Type), 10)] = attr.Value
 		} else {
 			results["unknown_"+strconv.FormatInt(int64(attr.Type), 10)] = attr.Value
 		}
@@ -297,48 +750,145 @@ func (p *Packet) GetAttributes() map[string]interface{} {
 	return results
 }
 
-// Encode encodes the packet to wire format. If there is an error encoding the
-// packet, nil and an error is returned.
-func (p *Packet) Encode() ([]byte, error) {
+// encodeAttributes encodes the packet's attributes to wire format. If the
+// packet has a Message-Authenticator attribute, its value is left zeroed and
+// msgAuth is the offset of the value in attrs; otherwise it is -1.
+//
+// Attributes of a parsed packet that are not modified are written as they
+// were received, including the grouping of vendor sub-attributes into
+// Vendor-Specific attributes.
+func (p *Packet) encodeAttributes() (attrs []byte, msgAuth int, err error) {
 	var bufferAttrs bytes.Buffer
-	for _, attr := range p.Attributes {
-		codec := p.Dictionary.Codec(attr.Type)
-		wire, err := codec.Encode(p, attr.Value)
-		if err != nil {
-			return nil, err
+	msgAuth = -1
+	for i := 0; i < len(p.Attributes); i++ {
+		attr := p.Attributes[i]
+		if span := p.unchangedSpan(i); span != nil {
+			bufferAttrs.Write(span.data)
+			i += len(span.attrs) - 1
+			continue
+		}
+
+		codec := AttributeUnknown
+		concat := false
+		if entry := p.Dictionary.lookup(attr); entry != nil {
+			codec = entry.Codec
+			concat = entry.Concat
+		}
+		var wire []byte
+		if attr.Invalid {
+			raw, ok := attr.Value.([]byte)
+			if !ok {
+				return nil, -1, errors.New("radius: value of invalid attribute must be []byte")
+			}
+			wire = raw
+		} else if wire, err = codec.Encode(p, attr.Value); err != nil {
+			return nil, -1, err
+		}
+		if attr.Vendor != 0 {
+			// Vendor-Specific wrapper: type, length, vendor ID, followed by
+			// the sub-attribute in the vendor's format.
+			format := p.Dictionary.vendorFormat(attr.Vendor)
+			maxChunk := 253 - 4 - format.headerSize()
+			if len(wire) > maxChunk && !concat {
+				return nil, -1, errors.New("radius: encoded attribute is too long")
+			}
+			for _, chunk := range splitValue(wire, maxChunk) {
+				bufferAttrs.WriteByte(26)
+				bufferAttrs.WriteByte(byte(len(chunk) + 6 + format.headerSize()))
+				binary.Write(&bufferAttrs, binary.BigEndian, attr.Vendor)
+				format.appendHeader(&bufferAttrs, attr.Type, len(chunk))
+				bufferAttrs.Write(chunk)
+			}
+			continue
 		}
-		if len(wire) > 253 {
-			return nil, errors.New("radius: encoded attribute is too long")
+		if isExtendedType(attr.Type) {
+			if err := encodeExtended(&bufferAttrs, attr, wire); err != nil {
+				return nil, -1, err
+			}
+			continue
+		}
+		if len(wire) > 253 && !concat {
+			return nil, -1, errors.New("radius: encoded attribute is too long")
+		}
+		if attr.Type == attrMessageAuthenticator && msgAuth < 0 {
+			if len(wire) != md5.Size {
+				return nil, -1, errors.New("radius: invalid Message-Authenticator attribute length")
+			}
+			msgAuth = bufferAttrs.Len() + 2
+		}
+		for _, chunk := range splitValue(wire, 253) {
+			bufferAttrs.WriteByte(attr.Type)
+			bufferAttrs.WriteByte(byte(len(chunk) + 2))
+			bufferAttrs.Write(chunk)
 		}
-		bufferAttrs.WriteByte(attr.Type)
-		bufferAttrs.WriteByte(byte(len(wire) + 2))
-		bufferAttrs.Write(wire)
 	}
+	return bufferAttrs.Bytes(), msgAuth, nil
+}
 
-	length := 1 + 1 + 2 + 16 + bufferAttrs.Len()
+// splitValue splits an encoded attribute value into chunks of at most
+// maxChunk bytes. An empty value results in a single empty chunk.
+func splitValue(value []byte, maxChunk int) [][]byte {
+	chunks := [][]byte{value[:len(value):len(value)]}
+	for len(value) > maxChunk {
+		chunks[len(chunks)-1] = value[:maxChunk]
+		value = value[maxChunk:]
+		chunks = append(chunks, value)
+	}
+	return chunks
+}
+
+// Encode encodes the packet to wire format. If there is an error encoding the
+// packet, nil and an error is returned.
+//
+// If the packet contains a Message-Authenticator attribute, its value is
+// calculated as described in RFC 3579 section 3.2 before the packet's
+// authenticator. Status-Server packets, which must carry one (RFC 5997
+// section 3), are encoded with a Message-Authenticator if they do not have
+// one.
+func (p *Packet) Encode() ([]byte, error) {
+	if p.Code == CodeStatusServer && p.messageAuthenticatorAttr() == nil {
+		withAuth := *p
+		withAuth.Attributes = append(p.Attributes[:len(p.Attributes):len(p.Attributes)], &Attribute{Type: attrMessageAuthenticator})
+		return withAuth.Encode()
+	}
+
+	attrs, msgAuth, err := p.encodeAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	length := 1 + 1 + 2 + 16 + len(attrs)
 	if length > maxPacketSize {
 		return nil, errors.New("radius: encoded packet is too long")
 	}
 
+	if msgAuth >= 0 {
+		authenticator := p.Authenticator[:]
+		if p.Code.hasZeroAuthenticator() {
+			authenticator = make([]byte, 16)
+		}
+		copy(attrs[msgAuth:], messageAuthenticator(p.Code, p.Identifier, authenticator, attrs, p.Secret))
+	}
+
 	var buffer bytes.Buffer
 	buffer.Grow(length)
 	buffer.WriteByte(byte(p.Code))
 	buffer.WriteByte(p.Identifier)
 	binary.Write(&buffer, binary.BigEndian, uint16(length))
 
-	switch p.Code {
-	case CodeAccessRequest, CodeStatusServer:
+	switch {
+	case p.Code == CodeAccessRequest || p.Code == CodeStatusServer:
 		buffer.Write(p.Authenticator[:])
-	case CodeAccessAccept, CodeAccessReject, CodeAccountingRequest, CodeAccountingResponse, CodeAccessChallenge:
+	case p.Code.isResponse() || p.Code.hasZeroAuthenticator():
 		hash := md5.New()
 		hash.Write(buffer.Bytes())
-		if p.Code == CodeAccountingRequest {
+		if p.Code.hasZeroAuthenticator() {
 			var nul [16]byte
 			hash.Write(nul[:])
 		} else {
 			hash.Write(p.Authenticator[:])
 		}
-		hash.Write(bufferAttrs.Bytes())
+		hash.Write(attrs)
 		hash.Write(p.Secret)
 
 		var sum [md5.Size]byte
@@ -347,7 +897,7 @@ func (p *Packet) Encode() ([]byte, error) {
 		return nil, errors.New("radius: unknown Packet code")
 	}
 
-	buffer.ReadFrom(&bufferAttrs)
+	buffer.Write(attrs)
 
 	return buffer.Bytes(), nil
 }
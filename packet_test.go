@@ -2,7 +2,10 @@ package radius_test
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
 	"net"
+	"strings"
 	"testing"
 
 	"github.com/runner-mei/radius"
@@ -47,11 +50,17 @@ func Test_Huawei(t *testing.T) {
 	if p.String("User-Name") != "201713893" {
 		t.Error("expecting User-Name = 201713893")
 	}
-	if p.String("User-Password") != "arctangent" {
-		t.Error("expecting User-Password = arctangent, actual is", p.String("User-Password"))
+	if _, _, ok := p.PAP(); ok {
+		t.Error("expecting PAP credentials to be absent")
 	}
-	if username, password, ok := p.PAP(); !ok || username != "nemo" || password != "arctangent" {
-		t.Error("PAP values do not match attributes")
+	if username, ident, _, challenge, ok := p.CHAP(); !ok || username != "201713893" || ident != 0x28 || !bytes.Equal(challenge, request[4:20]) {
+		t.Error("CHAP values do not match attributes")
+	}
+	if !p.VerifyCHAP("808206") {
+		t.Error("expecting CHAP password 808206 to be valid")
+	}
+	if p.VerifyCHAP("arctangent") {
+		t.Error("expecting CHAP password arctangent to be invalid")
 	}
 	if ip := p.Value("NAS-IP-Address").(net.IP); !ip.Equal(net.ParseIP("192.168.1.182")) {
 		t.Error("expecting NAS-IP-Address = 192.168.1.182, actual is", ip.String())
@@ -190,3 +199,739 @@ func Test_RFC2865_7_2(t *testing.T) {
 		t.Fatal("expecting Framed-Protocol = 1")
 	}
 }
+
+func Test_RFC2865_UserPassword_Long(t *testing.T) {
+	secret := []byte("xyzzy5461")
+	authenticator := [16]byte{
+		0x0f, 0x40, 0x3f, 0x94, 0x73, 0x97, 0x80, 0x57, 0xbd, 0x83, 0xd5, 0xcb,
+		0x98, 0xf4, 0x22, 0x7a,
+	}
+
+	for _, password := range []string{
+		"arctangent",
+		"arctangent-arctangent",
+		"correct horse battery staple, twice: correct horse battery staple",
+		strings.Repeat("x", 128),
+	} {
+		p := radius.Packet{
+			Code:          radius.CodeAccessRequest,
+			Authenticator: authenticator,
+			Secret:        secret,
+			Dictionary:    radius.Builtin,
+		}
+		p.Add("User-Name", "nemo")
+		p.Add("User-Password", password)
+
+		wire, err := p.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// User-Name is 6 bytes long and immediately follows the header.
+		hidden := wire[20+6+2:]
+		if len(hidden)%16 != 0 || len(hidden) < len(password) {
+			t.Fatalf("invalid hidden User-Password length %d for %d byte password", len(hidden), len(password))
+		}
+
+		// Verify the chaining manually: c(i) = p(i) xor MD5(S + c(i-1)).
+		prev := authenticator[:]
+		for i := 0; i < len(hidden); i += 16 {
+			mask := md5.Sum(append(append([]byte{}, secret...), prev...))
+			for j := 0; j < 16; j++ {
+				var plain byte
+				if i+j < len(password) {
+					plain = password[i+j]
+				}
+				if hidden[i+j]^mask[j] != plain {
+					t.Fatalf("block %d of hidden password is incorrect", i/16)
+				}
+			}
+			prev = hidden[i : i+16]
+		}
+
+		q, err := radius.Parse(wire, secret, radius.Builtin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.String("User-Password") != password {
+			t.Fatalf("expecting User-Password = %q, actual is %q", password, q.String("User-Password"))
+		}
+	}
+
+	p := radius.New(radius.CodeAccessRequest, secret)
+	p.Add("User-Password", strings.Repeat("x", 129))
+	if _, err := p.Encode(); err == nil {
+		t.Fatal("expecting 129 byte User-Password to fail encoding")
+	}
+}
+
+func Test_VendorSpecific(t *testing.T) {
+	dict := &radius.Dictionary{}
+	dict.RegisterVendor("default", 1)
+	dict.MustRegister("User-Name", 1, radius.AttributeText)
+	dict.MustRegister("Vendor-Specific", 26, radius.AttributeString)
+	dict.RegisterVendor("Huawei", 2011)
+	huawei := dict.Vendor("Huawei")
+	huawei.MustRegister("Huawei-Input-Burst-Size", 1, radius.AttributeInteger)
+	huawei.MustRegister("Huawei-Domain-Name", 138, radius.AttributeText)
+
+	if huawei.Codec(1) != radius.AttributeInteger || dict.Codec(1) != radius.AttributeText {
+		t.Fatal("expecting codecs to be looked up by vendor")
+	}
+
+	p := radius.New(radius.CodeAccessRequest, []byte("secret"))
+	p.Dictionary = dict
+	p.Add("User-Name", "nemo")
+	if err := p.Add("Huawei-Input-Burst-Size", uint32(0x01020304)); err != nil {
+		t.Fatal(err)
+	}
+	p.Add("Huawei-Domain-Name", "isp")
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x01, 0x06, 0x6e, 0x65, 0x6d, 0x6f,
+		0x1a, 0x0c, 0x00, 0x00, 0x07, 0xdb, 0x01, 0x06, 0x01, 0x02, 0x03, 0x04,
+		0x1a, 0x0b, 0x00, 0x00, 0x07, 0xdb, 0x8a, 0x05, 0x69, 0x73, 0x70,
+	}
+	if !bytes.Equal(wire[20:], expected) {
+		t.Fatalf("unexpected attributes % x", wire[20:])
+	}
+
+	q, err := radius.Parse(wire, p.Secret, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Attributes) != 3 {
+		t.Fatal("expecting 3 attributes, actual is", len(q.Attributes))
+	}
+	if attr := q.Attr("Huawei-Input-Burst-Size"); attr == nil || attr.Vendor != 2011 || attr.Value.(uint32) != 0x01020304 {
+		t.Fatal("expecting Huawei-Input-Burst-Size = 0x01020304")
+	}
+	if q.String("Huawei-Domain-Name") != "isp" {
+		t.Fatal("expecting Huawei-Domain-Name = isp")
+	}
+
+	// Vendors which are not registered are kept as opaque Vendor-Specific
+	// attributes.
+	unknown := []byte{
+		0x01, 0x00, 0x00, 0x1e, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x1a, 0x0a, 0x00, 0x00, 0x00, 0x09, 0x01, 0x04, 0x61, 0x62,
+	}
+	r, err := radius.Parse(unknown, p.Secret, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, ok := r.Value("Vendor-Specific").([]byte); !ok || !bytes.Equal(raw, unknown[22:]) {
+		t.Fatal("expecting opaque Vendor-Specific attribute")
+	}
+}
+
+func Test_RFC3579_MessageAuthenticator(t *testing.T) {
+	secret := []byte("xyzzy5461")
+
+	p := radius.New(radius.CodeAccessRequest, secret)
+	p.Add("User-Name", "nemo")
+	p.Add("Message-Authenticator", nil)
+	p.Add("NAS-Port", uint32(3))
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Recalculate the HMAC-MD5 over the packet with the value zeroed.
+	offset := 20 + 6 + 2
+	zeroed := append([]byte{}, wire...)
+	copy(zeroed[offset:offset+16], make([]byte, 16))
+	mac := hmac.New(md5.New, secret)
+	mac.Write(zeroed)
+	if !bytes.Equal(mac.Sum(nil), wire[offset:offset+16]) {
+		t.Fatal("incorrect Message-Authenticator")
+	}
+
+	request, err := radius.Parse(wire, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !request.IsMessageAuthentic(nil) {
+		t.Fatal("expecting request Message-Authenticator to be valid")
+	}
+	wire[len(wire)-1]++
+	tampered, err := radius.Parse(wire, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tampered.IsMessageAuthentic(nil) {
+		t.Fatal("expecting tampered request Message-Authenticator to be invalid")
+	}
+
+	q := radius.Packet{
+		Code:          radius.CodeAccessAccept,
+		Identifier:    request.Identifier,
+		Authenticator: request.Authenticator,
+		Secret:        secret,
+		Dictionary:    radius.Builtin,
+	}
+	q.Add("Message-Authenticator", nil)
+	q.Add("Reply-Message", "Welcome")
+	wire, err = q.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := radius.Parse(wire, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.IsAuthentic(request) {
+		t.Fatal("expecting response to be authentic")
+	}
+	if !response.IsMessageAuthentic(request) {
+		t.Fatal("expecting response Message-Authenticator to be valid")
+	}
+	other := *request
+	other.Authenticator[0]++
+	if response.IsMessageAuthentic(&other) {
+		t.Fatal("expecting response Message-Authenticator to depend on the request")
+	}
+}
+
+func Test_RFC2868_DynamicVLAN(t *testing.T) {
+	secret := []byte("xyzzy5461")
+	request := radius.New(radius.CodeAccessRequest, secret)
+	request.Add("User-Name", "nemo")
+
+	response := radius.Packet{
+		Code:          radius.CodeAccessAccept,
+		Identifier:    request.Identifier,
+		Authenticator: request.Authenticator,
+		Secret:        secret,
+		Dictionary:    radius.Builtin,
+	}
+	// VLAN (13) over IEEE-802 (6)
+	response.Add("Tunnel-Type", uint32(13))
+	response.Add("Tunnel-Medium-Type", radius.Tagged{Tag: 1, Value: uint32(6)})
+	response.Add("Tunnel-Private-Group-Id", "100")
+	response.Add("Tunnel-Password", radius.Tagged{Tag: 2, Value: "arctangent"})
+
+	wire, err := response.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x40, 0x06, 0x00, 0x00, 0x00, 0x0d,
+		0x41, 0x06, 0x01, 0x00, 0x00, 0x06,
+		0x51, 0x05, 0x31, 0x30, 0x30,
+	}
+	if !bytes.Equal(wire[20:20+len(expected)], expected) {
+		t.Fatalf("unexpected attributes % x", wire[20:])
+	}
+
+	received, err := radius.ParseResponse(wire, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !received.IsAuthentic(request) {
+		t.Fatal("expecting response to be authentic")
+	}
+	if v := received.Value("Tunnel-Type").(radius.Tagged); v.Tag != 0 || v.Value.(uint32) != 13 {
+		t.Fatal("expecting Tunnel-Type = 0:13, actual is", v)
+	}
+	if v := received.Value("Tunnel-Medium-Type").(radius.Tagged); v.Tag != 1 || v.Value.(uint32) != 6 {
+		t.Fatal("expecting Tunnel-Medium-Type = 1:6, actual is", v)
+	}
+	if received.String("Tunnel-Private-Group-Id") != "100" {
+		t.Fatal("expecting Tunnel-Private-Group-Id = 100, actual is", received.String("Tunnel-Private-Group-Id"))
+	}
+	if v := received.Value("Tunnel-Password").(radius.Tagged); v.Tag != 2 || v.Value.(string) != "arctangent" {
+		t.Fatal("expecting Tunnel-Password = 2:arctangent, actual is", v)
+	}
+
+	// Re-encoding the parsed values yields the same tagged attributes.
+	received.Authenticator = request.Authenticator
+	rewire, err := received.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rewire[20:20+len(expected)], expected) {
+		t.Fatalf("unexpected re-encoded attributes % x", rewire[20:])
+	}
+}
+
+func Test_RFC3162_IPv6(t *testing.T) {
+	secret := []byte("xyzzy5461")
+	_, prefix, _ := net.ParseCIDR("2001:db8:1::/48")
+	_, delegated, _ := net.ParseCIDR("2001:db8:ff00::/56")
+	ifid := []byte{0x02, 0x00, 0x5e, 0xff, 0xfe, 0x00, 0x53, 0x01}
+
+	p := radius.New(radius.CodeAccessRequest, secret)
+	p.Add("NAS-IPv6-Address", net.ParseIP("2001:db8::1"))
+	p.Add("Framed-Interface-Id", ifid)
+	p.Add("Framed-IPv6-Prefix", prefix)
+	p.Add("Delegated-IPv6-Prefix", delegated)
+	p.Add("Framed-IPv6-Route", "2001:db8:2::/64 2001:db8::1 1")
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Framed-IPv6-Prefix only carries the significant bytes of the prefix.
+	expected := []byte{0x61, 0x0a, 0x00, 0x30, 0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01}
+	if !bytes.Contains(wire, expected) {
+		t.Fatalf("expecting Framed-IPv6-Prefix % x in % x", expected, wire)
+	}
+
+	q, err := radius.Parse(wire, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := q.Value("NAS-IPv6-Address").(net.IP); !ip.Equal(net.ParseIP("2001:db8::1")) {
+		t.Fatal("expecting NAS-IPv6-Address = 2001:db8::1, actual is", ip)
+	}
+	if v := q.Value("Framed-Interface-Id").([]byte); !bytes.Equal(v, ifid) {
+		t.Fatal("incorrect Framed-Interface-Id", v)
+	}
+	if v := q.Value("Framed-IPv6-Prefix").(*net.IPNet); v.String() != "2001:db8:1::/48" {
+		t.Fatal("expecting Framed-IPv6-Prefix = 2001:db8:1::/48, actual is", v)
+	}
+	if v := q.Value("Delegated-IPv6-Prefix").(*net.IPNet); v.String() != "2001:db8:ff00::/56" {
+		t.Fatal("expecting Delegated-IPv6-Prefix = 2001:db8:ff00::/56, actual is", v)
+	}
+	if q.String("Framed-IPv6-Route") != "2001:db8:2::/64 2001:db8::1 1" {
+		t.Fatal("incorrect Framed-IPv6-Route", q.String("Framed-IPv6-Route"))
+	}
+
+	// IPv4-mapped addresses are IPv6 addresses, 4 byte IPv4 addresses are
+	// not.
+	mapped := radius.New(radius.CodeAccessRequest, secret)
+	mapped.Add("NAS-IPv6-Address", net.ParseIP("::ffff:192.168.1.16"))
+	mapped.Add("Framed-IPv6-Prefix", &net.IPNet{IP: net.ParseIP("::ffff:192.168.1.0"), Mask: net.CIDRMask(120, 128)})
+	wire, err = mapped.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err = radius.Parse(wire, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := q.Value("NAS-IPv6-Address").(net.IP); !ip.Equal(net.ParseIP("::ffff:192.168.1.16")) {
+		t.Fatal("expecting NAS-IPv6-Address = ::ffff:192.168.1.16, actual is", ip)
+	}
+	if v := q.Value("Framed-IPv6-Prefix").(*net.IPNet); !v.IP.Equal(net.ParseIP("::ffff:192.168.1.0")) {
+		t.Fatal("expecting Framed-IPv6-Prefix = ::ffff:192.168.1.0/120, actual is", v)
+	}
+
+	if err := p.Add("NAS-IPv6-Address", net.ParseIP("192.168.1.16").To4()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Encode(); err == nil {
+		t.Fatal("expecting IPv4 NAS-IPv6-Address to fail encoding")
+	}
+}
+
+func Test_RFC6929_Extended(t *testing.T) {
+	dict := &radius.Dictionary{}
+	dict.RegisterVendor("default", 1)
+	dict.MustRegister("User-Name", 1, radius.AttributeText)
+	for _, line := range []string{
+		"ATTRIBUTE Frag-Status 241.1 integer",
+		"ATTRIBUTE Example-Counter 241.2 integer64",
+		"ATTRIBUTE Example-Prefix 242.1 ipv4prefix",
+		"ATTRIBUTE Example-Long 245.1 octets",
+	} {
+		if !dict.ParseAttrs(strings.Fields(line)) {
+			t.Fatal("failed to parse", line)
+		}
+	}
+
+	long := bytes.Repeat([]byte("0123456789"), 60)
+	_, prefix, _ := net.ParseCIDR("192.0.2.0/24")
+
+	p := radius.New(radius.CodeAccessRequest, []byte("secret"))
+	p.Dictionary = dict
+	p.Add("User-Name", "nemo")
+	p.Add("Frag-Status", uint32(1))
+	p.Add("Example-Counter", uint64(1)<<40)
+	p.Add("Example-Prefix", prefix)
+	p.Add("Example-Long", long)
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := wire[20+6:]
+	if !bytes.Equal(attrs[:7], []byte{0xf1, 0x07, 0x01, 0x00, 0x00, 0x00, 0x01}) {
+		t.Fatalf("unexpected Frag-Status % x", attrs[:7])
+	}
+	// 600 bytes are split into fragments of 251, 251 and 98 bytes.
+	attrs = attrs[7+11+9:]
+	if attrs[0] != 0xf5 || attrs[1] != 0xff || attrs[2] != 0x01 || attrs[3] != 0x80 {
+		t.Fatalf("unexpected first fragment header % x", attrs[:4])
+	}
+	attrs = attrs[255:]
+	if attrs[0] != 0xf5 || attrs[1] != 0xff || attrs[2] != 0x01 || attrs[3] != 0x80 {
+		t.Fatalf("unexpected second fragment header % x", attrs[:4])
+	}
+	attrs = attrs[255:]
+	if len(attrs) != 102 || attrs[1] != 102 || attrs[3] != 0x00 {
+		t.Fatalf("unexpected last fragment header % x", attrs[:4])
+	}
+
+	q, err := radius.Parse(wire, p.Secret, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Attributes) != 5 {
+		t.Fatal("expecting 5 attributes, actual is", len(q.Attributes))
+	}
+	if attr := q.Attr("Frag-Status"); attr == nil || attr.Type != 241 || attr.ExtendedType != 1 || attr.Value.(uint32) != 1 {
+		t.Fatal("expecting Frag-Status = 1")
+	}
+	if q.Value("Example-Counter").(uint64) != uint64(1)<<40 {
+		t.Fatal("incorrect Example-Counter", q.Value("Example-Counter"))
+	}
+	if v := q.Value("Example-Prefix").(*net.IPNet); v.String() != "192.0.2.0/24" {
+		t.Fatal("incorrect Example-Prefix", v)
+	}
+	if !bytes.Equal(q.Value("Example-Long").([]byte), long) {
+		t.Fatal("incorrect reassembled Example-Long")
+	}
+
+	// A fragment with the More flag must be followed by another fragment.
+	truncated := append([]byte{}, wire[:len(wire)-102]...)
+	truncated[2], truncated[3] = byte(len(truncated)>>8), byte(len(truncated))
+	if _, err := radius.Parse(truncated, p.Secret, dict); err == nil {
+		t.Fatal("expecting truncated long extended attribute to fail parsing")
+	}
+}
+
+func Test_NamedValues(t *testing.T) {
+	p := radius.New(radius.CodeAccountingRequest, []byte("secret"))
+	if err := p.Add("Acct-Status-Type", "Start"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Add("Acct-Status-Type", "Begin"); err == nil {
+		t.Fatal("expecting unknown value name to fail")
+	}
+	p.Add("Service-Type", uint32(2))
+	p.Add("Tunnel-Type", radius.Tagged{Tag: 1, Value: "VLAN"})
+
+	if v := p.Value("Acct-Status-Type"); v != uint32(1) {
+		t.Fatal("expecting Acct-Status-Type = 1, actual is", v)
+	}
+	if p.String("Acct-Status-Type") != "Start" {
+		t.Fatal("expecting Acct-Status-Type = Start, actual is", p.String("Acct-Status-Type"))
+	}
+	if p.String("Service-Type") != "Framed-User" {
+		t.Fatal("expecting Service-Type = Framed-User, actual is", p.String("Service-Type"))
+	}
+	if p.String("Tunnel-Type") != "VLAN" {
+		t.Fatal("expecting Tunnel-Type = VLAN, actual is", p.String("Tunnel-Type"))
+	}
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := radius.Parse(wire, p.Secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.String("Acct-Status-Type") != "Start" || q.Value("Acct-Status-Type").(uint32) != 1 {
+		t.Fatal("expecting parsed Acct-Status-Type = Start")
+	}
+	if v := q.Value("Tunnel-Type").(radius.Tagged); v.Tag != 1 || v.Value.(uint32) != 13 {
+		t.Fatal("expecting parsed Tunnel-Type = 1:13, actual is", v)
+	}
+
+	dict := &radius.Dictionary{}
+	dict.RegisterVendor("default", 1)
+	dict.ParseAttrs(strings.Fields("ATTRIBUTE Example-Mode 1 integer"))
+	if !dict.ParseValue(strings.Fields("VALUE Example-Mode Turbo 0x10")) {
+		t.Fatal("expecting VALUE line to parse")
+	}
+	if dict.ParseValue(strings.Fields("VALUE Example-Missing Turbo 1")) {
+		t.Fatal("expecting VALUE line of unknown attribute to fail")
+	}
+	attr, err := dict.Attr("Example-Mode", "Turbo")
+	if err != nil || attr.Value != uint32(16) {
+		t.Fatal("expecting Example-Mode = 16", err)
+	}
+}
+
+func Test_Concat(t *testing.T) {
+	dict := &radius.Dictionary{}
+	err := dict.LoadDictsReader(strings.NewReader(`
+ATTRIBUTE	User-Name		1	string
+ATTRIBUTE	EAP-Message		79	octets	concat
+VENDOR		Example			32473
+BEGIN-VENDOR	Example
+ATTRIBUTE	Example-Certificate	1	octets	concat
+END-VENDOR	Example
+`), "concat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eap := bytes.Repeat([]byte("0123456789"), 60)
+	cert := bytes.Repeat([]byte("abcdefghij"), 30)
+
+	p := radius.New(radius.CodeAccessRequest, []byte("secret"))
+	p.Dictionary = dict
+	p.Add("User-Name", "nemo")
+	p.Add("EAP-Message", eap)
+	p.Add("Example-Certificate", cert)
+	p.Add("User-Name", "nemo2")
+
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// EAP-Message: 253 + 253 + 94; Example-Certificate: 247 + 53
+	if len(b) != 20+6+(255+255+96)+(255+61)+7 {
+		t.Fatalf("unexpected packet length %d", len(b))
+	}
+
+	q, err := radius.Parse(b, []byte("secret"), dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Attributes) != 4 {
+		t.Fatalf("expecting 4 attributes, got %d", len(q.Attributes))
+	}
+	if q.Attributes[1].Type != 79 || !bytes.Equal(q.Attributes[1].Value.([]byte), eap) {
+		t.Fatal("expecting reassembled EAP-Message as second attribute")
+	}
+	if q.Attributes[2].Vendor != 32473 || !bytes.Equal(q.Attributes[2].Value.([]byte), cert) {
+		t.Fatal("expecting reassembled Example-Certificate as third attribute")
+	}
+	if name, _ := dict.AttrName(q.Attributes[3]); name != "User-Name" {
+		t.Fatal("expecting User-Name as last attribute")
+	}
+
+	builtin := radius.New(radius.CodeAccessChallenge, []byte("secret"))
+	builtin.Add("EAP-Message", eap)
+	if _, err := builtin.Encode(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	p := radius.New(radius.CodeAccessRequest, []byte("secret"))
+	p.Add("User-Name", "nemo")
+	p.Add("User-Password", "arctangent")
+	p.Add("NAS-Identifier", "nas")
+	p.Add("State", []byte("1"))
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Add("CHAP-Password", make([]byte, 17))
+	p.Add("State", []byte("2"))
+	p.Add("Reply-Message", "hello")
+	var attrs []*radius.Attribute
+	for _, attr := range p.Attributes {
+		if attr.Type != 32 {
+			attrs = append(attrs, attr)
+		}
+	}
+	p.Attributes = attrs
+
+	err, ok := p.Validate().(*radius.ValidationError)
+	if !ok {
+		t.Fatalf("expecting *ValidationError, got %v", err)
+	}
+	expected := []string{
+		"Reply-Message is not permitted",
+		"State must not appear more than once",
+		"exactly one of User-Password, CHAP-Password, EAP-Message and MS-CHAP-Response or MS-CHAP2-Response is required",
+		"NAS-IP-Address, NAS-IPv6-Address or NAS-Identifier is required",
+	}
+	if strings.Join(err.Violations, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected violations %q", err.Violations)
+	}
+
+	// MS-CHAP credentials are Microsoft vendor-specific attributes.
+	mschap := radius.New(radius.CodeAccessRequest, []byte("secret"))
+	mschap.Add("User-Name", "nemo")
+	mschap.Add("NAS-Identifier", "nas")
+	mschap.Add("MS-CHAP-Challenge", make([]byte, 16))
+	mschap.Add("MS-CHAP2-Response", make([]byte, 50))
+	if err := mschap.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Vendor-Specific attributes are not checked, whether their vendor is
+	// registered or not.
+	reject := radius.New(radius.CodeAccessReject, []byte("secret"))
+	reject.Add("MS-CHAP-Error", "E=691 R=0")
+	reject.Attributes = append(reject.Attributes, &radius.Attribute{Type: 26, Value: []byte{0, 0, 0x7e, 0xd9, 1, 3, 0}})
+	if err := reject.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	coa := radius.New(radius.CodeCoAACK, []byte("secret"))
+	coa.Add("Session-Timeout", uint32(60))
+	if err := coa.Validate(); err == nil || err.Error() != "radius: invalid CoA-ACK: Session-Timeout is not permitted" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	status := radius.New(radius.CodeStatusServer, []byte("secret"))
+	if err := status.Validate(); err == nil || err.Error() != "radius: invalid Status-Server: Message-Authenticator is required" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	acct := radius.New(radius.CodeAccountingRequest, []byte("secret"))
+	acct.Add("Acct-Status-Type", "Start")
+	if err := acct.Validate(); err == nil || err.Error() != "radius: invalid Accounting-Request: Acct-Session-Id is required; NAS-IP-Address, NAS-IPv6-Address or NAS-Identifier is required" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	acct.Add("Acct-Session-Id", "0001")
+	acct.Add("NAS-IPv6-Address", net.ParseIP("2001:db8::1"))
+	if err := acct.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	accept := radius.New(radius.CodeAccessAccept, []byte("secret"))
+	accept.Add("Login-Service", uint32(0))
+	accept.Add("Login-Service", uint32(1))
+	if err := accept.Validate(); err == nil || err.Error() != "radius: invalid Access-Accept: Login-Service must not appear more than once" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func Test_ParseLenient(t *testing.T) {
+	secret := []byte("secret")
+	p := radius.New(radius.CodeAccountingRequest, secret)
+	p.Add("User-Name", "nemo")
+	p.Add("Acct-Status-Type", "Start")
+	p.Add("Acct-Session-Id", "1")
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replace the 4 byte Acct-Status-Type with a 3 byte one, and
+	// recalculate the length and authenticator.
+	i := bytes.Index(b, []byte{40, 6, 0, 0, 0, 1})
+	b = append(b[:i:i], append([]byte{40, 5, 0, 0, 1}, b[i+6:]...)...)
+	b[3]--
+	hash := md5.New()
+	hash.Write(b[:4])
+	hash.Write(make([]byte, 16))
+	hash.Write(b[20:])
+	hash.Write(secret)
+	copy(b[4:20], hash.Sum(nil))
+
+	if _, err := radius.Parse(b, secret, radius.Builtin); err == nil {
+		t.Fatal("expecting Parse to fail")
+	}
+	q, err := radius.ParseLenient(b, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Attributes) != 3 || len(q.Errors) != 1 || q.Errors[0].Attribute != q.Attributes[1] {
+		t.Fatalf("expecting a single invalid attribute, got %v", q.Errors)
+	}
+	if !q.Attributes[1].Invalid || !bytes.Equal(q.Attributes[1].Value.([]byte), []byte{0, 0, 1}) {
+		t.Fatal("expecting invalid Acct-Status-Type to keep its value")
+	}
+	if q.String("User-Name") != "nemo" {
+		t.Fatal("expecting User-Name = nemo")
+	}
+
+	encoded, err := q.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, b) {
+		t.Fatalf("expecting original packet, got %x", encoded)
+	}
+}
+
+func Test_ParseLenientVendorSpecific(t *testing.T) {
+	secret := []byte("secret")
+	b := []byte{4, 1, 0, 0}
+	b = append(b, make([]byte, 16)...)
+	b = append(b, 1, 6, 'n', 'e', 'm', 'o')
+	// MS-MPPE-Encryption-Policy and MS-MPPE-Encryption-Types in a single
+	// Vendor-Specific attribute
+	b = append(b, 26, 18, 0, 0, 0x01, 0x37, 7, 6, 0, 0, 0, 1, 8, 6, 0, 0, 0, 6)
+	b = append(b, 40, 6, 0, 0, 0, 1)
+	b = append(b, 41, 5, 0, 0, 1)
+	b = append(b, 44, 3, '1')
+	b[3] = byte(len(b))
+	hash := md5.New()
+	hash.Write(b[:4])
+	hash.Write(make([]byte, 16))
+	hash.Write(b[20:])
+	hash.Write(secret)
+	copy(b[4:20], hash.Sum(nil))
+
+	q, err := radius.ParseLenient(b, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Attributes) != 6 || len(q.Errors) != 1 {
+		t.Fatalf("expecting 6 attributes with 1 invalid, got %d, %v", len(q.Attributes), q.Errors)
+	}
+	encoded, err := q.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, b) {
+		t.Fatalf("expecting original packet %x, got %x", b, encoded)
+	}
+
+	// Modified attributes are encoded again.
+	q.Set("MS-MPPE-Encryption-Types", uint32(4))
+	encoded, err = q.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vsas := []byte{26, 12, 0, 0, 0x01, 0x37, 7, 6, 0, 0, 0, 1, 26, 12, 0, 0, 0x01, 0x37, 8, 6, 0, 0, 0, 4}
+	if !bytes.Contains(encoded, vsas) {
+		t.Fatalf("expecting separate Vendor-Specific attributes, got %x", encoded)
+	}
+	r, err := radius.ParseLenient(encoded, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsAuthentic(r) || r.Value("MS-MPPE-Encryption-Types") != uint32(4) || r.String("User-Name") != "nemo" {
+		t.Fatalf("unexpected packet %x", encoded)
+	}
+}
+
+func Test_ParseMalformed(t *testing.T) {
+	secret := []byte("secret")
+	p := radius.New(radius.CodeAccessRequest, secret)
+	p.Add("User-Name", "nemo")
+	b, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trailing octets beyond the Length field are ignored.
+	q, err := radius.Parse(append(b, 1, 1, 0, 0), secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Attributes) != 1 || q.String("User-Name") != "nemo" {
+		t.Fatal("expecting only User-Name = nemo")
+	}
+
+	short := append([]byte(nil), b...)
+	short[3]++
+	if _, err := radius.Parse(short, secret, radius.Builtin); err == nil {
+		t.Fatal("expecting error for Length beyond the data")
+	}
+
+	for _, attrs := range [][]byte{{1, 1}, {1, 0}, {1}, {1, 7, 'n'}} {
+		data := append(append([]byte(nil), b[:20]...), attrs...)
+		data[3] = byte(len(data))
+		if _, err := radius.Parse(data, secret, radius.Builtin); err == nil {
+			t.Fatalf("expecting error for attributes %x", attrs)
+		}
+	}
+}
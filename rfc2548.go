@@ -0,0 +1,288 @@
+package radius
+
+import (
+	"crypto/des"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+// VendorMicrosoft is the vendor ID of the Microsoft vendor-specific
+// attributes defined in RFC 2548.
+const VendorMicrosoft = 311
+
+func init() {
+	builtinOnce.Do(initDictionary)
+	Builtin.RegisterVendor("Microsoft", VendorMicrosoft)
+	ms := Builtin.Vendor("Microsoft")
+	ms.MustRegister("MS-CHAP-Response", 1, AttributeString)
+	ms.MustRegister("MS-CHAP-Error", 2, AttributeString)
+	ms.MustRegister("MS-CHAP-CPW-1", 3, AttributeString)
+	ms.MustRegister("MS-CHAP-CPW-2", 4, AttributeString)
+	ms.MustRegister("MS-CHAP-LM-Enc-PW", 5, AttributeString)
+	ms.MustRegister("MS-CHAP-NT-Enc-PW", 6, AttributeString)
+	ms.MustRegister("MS-MPPE-Encryption-Policy", 7, AttributeInteger)
+	ms.MustRegister("MS-MPPE-Encryption-Types", 8, AttributeInteger)
+	ms.MustRegister("MS-RAS-Vendor", 9, AttributeInteger)
+	ms.MustRegister("MS-CHAP-Domain", 10, AttributeString)
+	ms.MustRegister("MS-CHAP-Challenge", 11, AttributeString)
+	ms.MustRegister("MS-BAP-Usage", 13, AttributeInteger)
+	ms.MustRegister("MS-Link-Utilization-Threshold", 14, AttributeInteger)
+	ms.MustRegister("MS-Link-Drop-Time-Limit", 15, AttributeInteger)
+	ms.MustRegister("MS-MPPE-Send-Key", 16, rfc2548SaltEncrypted{})
+	ms.MustRegister("MS-MPPE-Recv-Key", 17, rfc2548SaltEncrypted{})
+	ms.MustRegister("MS-Old-ARAP-Password", 19, AttributeString)
+	ms.MustRegister("MS-New-ARAP-Password", 20, AttributeString)
+	ms.MustRegister("MS-ARAP-PW-Change-Reason", 21, AttributeInteger)
+	ms.MustRegister("MS-Filter", 22, AttributeString)
+	ms.MustRegister("MS-Acct-Auth-Type", 23, AttributeInteger)
+	ms.MustRegister("MS-Acct-EAP-Type", 24, AttributeInteger)
+	ms.MustRegister("MS-CHAP2-Response", 25, AttributeString)
+	ms.MustRegister("MS-CHAP2-Success", 26, AttributeString)
+	ms.MustRegister("MS-CHAP2-CPW", 27, AttributeString)
+	ms.MustRegister("MS-Primary-DNS-Server", 28, AttributeAddress)
+	ms.MustRegister("MS-Secondary-DNS-Server", 29, AttributeAddress)
+	ms.MustRegister("MS-Primary-NBNS-Server", 30, AttributeAddress)
+	ms.MustRegister("MS-Secondary-NBNS-Server", 31, AttributeAddress)
+	ms.MustRegister("MS-ARAP-Challenge", 33, AttributeString)
+}
+
+// rfc2548SaltEncrypted implements the salt encryption described in RFC 2548
+// section 2.4.2, which is used by MS-MPPE-Send-Key and MS-MPPE-Recv-Key. The
+// value is encrypted using the shared secret, the Request Authenticator and a
+// random salt. When encoding a response, Packet.Authenticator must hold the
+// Request Authenticator, as it does for packets written by ResponseWriter.
+type rfc2548SaltEncrypted struct{}
+
+func (rfc2548SaltEncrypted) Decode(p *Packet, value []byte) (interface{}, error) {
+	if p.Secret == nil {
+		return nil, errors.New("radius: salt encrypted attribute requires Packet.Secret")
+	}
+	if len(value) < 2+md5.Size || (len(value)-2)%md5.Size != 0 {
+		return nil, errors.New("radius: invalid salt encrypted attribute length")
+	}
+	plain := saltCrypt(p, value[:2], value[2:], false)
+	length := int(plain[0])
+	if length > len(plain)-1 {
+		return nil, errors.New("radius: invalid salt encrypted attribute key length")
+	}
+	return plain[1 : 1+length], nil
+}
+
+func (rfc2548SaltEncrypted) Encode(p *Packet, value interface{}) ([]byte, error) {
+	if p.Secret == nil {
+		return nil, errors.New("radius: salt encrypted attribute requires Packet.Secret")
+	}
+	var key []byte
+	if byteKey, ok := value.([]byte); !ok {
+		strKey, ok := value.(string)
+		if !ok {
+			return nil, errors.New("radius: salt encrypted attribute must be []byte or string")
+		}
+		key = []byte(strKey)
+	} else {
+		key = byteKey
+	}
+	if len(key) > 255 {
+		return nil, errors.New("radius: invalid salt encrypted attribute key length")
+	}
+
+	salt := make([]byte, 2)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	// The most significant bit of the salt must be set.
+	salt[0] |= 0x80
+
+	size := (1 + len(key) + md5.Size - 1) / md5.Size * md5.Size
+	plain := make([]byte, size)
+	plain[0] = byte(len(key))
+	copy(plain[1:], key)
+
+	return append(salt, saltCrypt(p, salt, plain, true)...), nil
+}
+
+// saltCrypt encrypts or decrypts data, whose length must be a multiple of 16,
+// using the salt encryption of RFC 2548 section 2.4.2:
+//
+//	b(1) = MD5(S + R + A)    c(1) = p(1) xor b(1)
+//	b(i) = MD5(S + c(i-1))   c(i) = p(i) xor b(i)
+func saltCrypt(p *Packet, salt, data []byte, encrypt bool) []byte {
+	out := make([]byte, len(data))
+
+	var mask [md5.Size]byte
+	hash := md5.New()
+	hash.Write(p.Secret)
+	hash.Write(p.Authenticator[:])
+	hash.Write(salt)
+	for i := 0; i < len(data); i += md5.Size {
+		hash.Sum(mask[0:0])
+		for j := 0; j < md5.Size; j++ {
+			out[i+j] = data[i+j] ^ mask[j]
+		}
+
+		hash.Reset()
+		hash.Write(p.Secret)
+		if encrypt {
+			hash.Write(out[i : i+md5.Size])
+		} else {
+			hash.Write(data[i : i+md5.Size])
+		}
+	}
+	return out
+}
+
+// NTPasswordHash returns the NT password hash of the given cleartext
+// password, which is the MD4 checksum of its UTF-16LE encoding (RFC 2759
+// section 8.3).
+func NTPasswordHash(password string) []byte {
+	encoded := utf16.Encode([]rune(password))
+	raw := make([]byte, len(encoded)*2)
+	for i, r := range encoded {
+		raw[i*2] = byte(r)
+		raw[i*2+1] = byte(r >> 8)
+	}
+	sum := md4Sum(raw)
+	return sum[:]
+}
+
+// mschapChallengeResponse implements ChallengeResponse of RFC 2759 section
+// 8.5: challenge is DES encrypted with three keys derived from the zero-padded
+// password hash.
+func mschapChallengeResponse(challenge, passwordHash []byte) []byte {
+	var zPasswordHash [21]byte
+	copy(zPasswordHash[:], passwordHash)
+
+	response := make([]byte, 24)
+	for i := 0; i < 3; i++ {
+		block, err := des.NewCipher(mschapDESKey(zPasswordHash[i*7 : i*7+7]))
+		if err != nil {
+			return nil
+		}
+		block.Encrypt(response[i*8:], challenge)
+	}
+	return response
+}
+
+// mschapDESKey expands a 7 byte key to an 8 byte DES key. The parity bits are
+// left unset since they are ignored by DES.
+func mschapDESKey(key []byte) []byte {
+	return []byte{
+		key[0] & 0xfe,
+		(key[0]<<7 | key[1]>>1) & 0xfe,
+		(key[1]<<6 | key[2]>>2) & 0xfe,
+		(key[2]<<5 | key[3]>>3) & 0xfe,
+		(key[3]<<4 | key[4]>>4) & 0xfe,
+		(key[4]<<3 | key[5]>>5) & 0xfe,
+		(key[5]<<2 | key[6]>>6) & 0xfe,
+		key[6] << 1,
+	}
+}
+
+// mschap2ChallengeHash implements ChallengeHash of RFC 2759 section 8.2.
+func mschap2ChallengeHash(peerChallenge, authenticatorChallenge []byte, username string) []byte {
+	// Only the user name, without any domain, is hashed.
+	if i := strings.LastIndexByte(username, '\\'); i > -1 {
+		username = username[i+1:]
+	}
+	hash := sha1.New()
+	hash.Write(peerChallenge)
+	hash.Write(authenticatorChallenge)
+	hash.Write([]byte(username))
+	return hash.Sum(nil)[:8]
+}
+
+// mschap2 returns the MS-CHAPv2 credentials of an Access-Request packet.
+func (p *Packet) mschap2() (username string, challenge, response []byte, ok bool) {
+	if p.Code != CodeAccessRequest {
+		return
+	}
+	username, valid := p.Value("User-Name").(string)
+	if !valid {
+		return
+	}
+	challenge, valid = p.Value("MS-CHAP-Challenge").([]byte)
+	if !valid || len(challenge) != 16 {
+		return
+	}
+	response, valid = p.Value("MS-CHAP2-Response").([]byte)
+	if !valid || len(response) != 50 {
+		return
+	}
+	ok = true
+	return
+}
+
+// VerifyMSCHAP returns if the packet's MS-CHAP-Response attribute is the
+// correct MS-CHAPv1 response (RFC 2433) to its MS-CHAP-Challenge attribute for
+// the given NT password hash. Only the NT-Response field is checked; false is
+// returned if the response does not include one.
+func (p *Packet) VerifyMSCHAP(ntHash []byte) bool {
+	if p.Code != CodeAccessRequest {
+		return false
+	}
+	challenge, ok := p.Value("MS-CHAP-Challenge").([]byte)
+	if !ok || len(challenge) != 8 {
+		return false
+	}
+	response, ok := p.Value("MS-CHAP-Response").([]byte)
+	if !ok || len(response) != 50 || response[1]&0x01 == 0 {
+		return false
+	}
+	expected := mschapChallengeResponse(challenge, ntHash)
+	return subtle.ConstantTimeCompare(expected, response[26:50]) == 1
+}
+
+// VerifyMSCHAP2 returns if the packet's MS-CHAP2-Response attribute is the
+// correct MS-CHAPv2 response (RFC 2759) to its MS-CHAP-Challenge attribute
+// for the given NT password hash.
+func (p *Packet) VerifyMSCHAP2(ntHash []byte) bool {
+	username, challenge, response, ok := p.mschap2()
+	if !ok {
+		return false
+	}
+	challengeHash := mschap2ChallengeHash(response[2:18], challenge, username)
+	expected := mschapChallengeResponse(challengeHash, ntHash)
+	return subtle.ConstantTimeCompare(expected, response[26:50]) == 1
+}
+
+var (
+	mschap2Magic1 = []byte("Magic server to client signing constant")
+	mschap2Magic2 = []byte("Pad to make it do more than one iteration")
+)
+
+// MSCHAP2Success returns the value of the MS-CHAP2-Success attribute that
+// should be sent in the Access-Accept to the packet, which contains the
+// authenticator response of RFC 2759 section 8.7 for the given NT password
+// hash.
+//
+// The response should only be sent after VerifyMSCHAP2 succeeds.
+func (p *Packet) MSCHAP2Success(ntHash []byte) ([]byte, error) {
+	username, challenge, response, ok := p.mschap2()
+	if !ok {
+		return nil, errors.New("radius: packet does not contain MS-CHAPv2 credentials")
+	}
+	passwordHashHash := md4Sum(ntHash)
+
+	hash := sha1.New()
+	hash.Write(passwordHashHash[:])
+	hash.Write(response[26:50])
+	hash.Write(mschap2Magic1)
+	digest := hash.Sum(nil)
+
+	hash.Reset()
+	hash.Write(digest)
+	hash.Write(mschap2ChallengeHash(response[2:18], challenge, username))
+	hash.Write(mschap2Magic2)
+	digest = hash.Sum(nil)
+
+	success := []byte{response[0]}
+	success = append(success, "S="...)
+	success = append(success, strings.ToUpper(hex.EncodeToString(digest))...)
+	return success, nil
+}
@@ -0,0 +1,145 @@
+package radius_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/runner-mei/radius"
+)
+
+func Test_RFC2759_MSCHAP2(t *testing.T) {
+	// Source: https://tools.ietf.org/html/rfc2759#section-9.2
+
+	authenticatorChallenge := []byte{
+		0x5B, 0x5D, 0x7C, 0x7D, 0x7B, 0x3F, 0x2F, 0x3E, 0x3C, 0x2C, 0x60, 0x21, 0x32, 0x26, 0x26, 0x28,
+	}
+	peerChallenge := []byte{
+		0x21, 0x40, 0x23, 0x24, 0x25, 0x5E, 0x26, 0x2A, 0x28, 0x29, 0x5F, 0x2B, 0x3A, 0x33, 0x7C, 0x7E,
+	}
+	ntResponse := []byte{
+		0x82, 0x30, 0x9E, 0xCD, 0x8D, 0x70, 0x8B, 0x5E, 0xA0, 0x8F, 0xAA, 0x39, 0x81, 0xCD, 0x83, 0x54,
+		0x42, 0x33, 0x11, 0x4A, 0x3D, 0x85, 0xD6, 0xDF,
+	}
+	passwordHash := []byte{
+		0x44, 0xEB, 0xBA, 0x8D, 0x53, 0x12, 0xB8, 0xD6, 0x11, 0x47, 0x44, 0x11, 0xF5, 0x69, 0x89, 0xAE,
+	}
+
+	if hash := radius.NTPasswordHash("clientPass"); !bytes.Equal(hash, passwordHash) {
+		t.Fatalf("incorrect NT password hash % x", hash)
+	}
+
+	response := []byte{0x01, 0x00}
+	response = append(response, peerChallenge...)
+	response = append(response, make([]byte, 8)...)
+	response = append(response, ntResponse...)
+
+	secret := []byte("secret")
+	p := radius.New(radius.CodeAccessRequest, secret)
+	p.Add("User-Name", "User")
+	p.Add("MS-CHAP-Challenge", authenticatorChallenge)
+	p.Add("MS-CHAP2-Response", response)
+
+	wire, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := radius.Parse(wire, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr := q.Attr("MS-CHAP2-Response"); attr == nil || attr.Vendor != radius.VendorMicrosoft {
+		t.Fatal("expecting MS-CHAP2-Response vendor-specific attribute")
+	}
+
+	if !q.VerifyMSCHAP2(radius.NTPasswordHash("clientPass")) {
+		t.Fatal("expecting MS-CHAPv2 response to be valid")
+	}
+	if q.VerifyMSCHAP2(radius.NTPasswordHash("serverPass")) {
+		t.Fatal("expecting MS-CHAPv2 response to be invalid")
+	}
+
+	success, err := q.MSCHAP2Success(passwordHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(success) != "\x01S=407A5589115FD0D6209F510FE9C04566932CDA56" {
+		t.Fatalf("incorrect MS-CHAP2-Success %q", success)
+	}
+}
+
+func Test_RFC2433_MSCHAP(t *testing.T) {
+	// The MS-CHAPv1 NT-Response is the ChallengeResponse of the challenge, so
+	// the RFC 2759 test vector can be reused with its challenge hash.
+	challenge := []byte{0xD0, 0x2E, 0x43, 0x86, 0xBC, 0xE9, 0x12, 0x26}
+	ntResponse := []byte{
+		0x82, 0x30, 0x9E, 0xCD, 0x8D, 0x70, 0x8B, 0x5E, 0xA0, 0x8F, 0xAA, 0x39, 0x81, 0xCD, 0x83, 0x54,
+		0x42, 0x33, 0x11, 0x4A, 0x3D, 0x85, 0xD6, 0xDF,
+	}
+
+	response := []byte{0x01, 0x01}
+	response = append(response, make([]byte, 24)...)
+	response = append(response, ntResponse...)
+
+	p := radius.New(radius.CodeAccessRequest, []byte("secret"))
+	p.Add("User-Name", "User")
+	p.Add("MS-CHAP-Challenge", challenge)
+	p.Add("MS-CHAP-Response", response)
+
+	if !p.VerifyMSCHAP(radius.NTPasswordHash("clientPass")) {
+		t.Fatal("expecting MS-CHAP response to be valid")
+	}
+	if p.VerifyMSCHAP(radius.NTPasswordHash("serverPass")) {
+		t.Fatal("expecting MS-CHAP response to be invalid")
+	}
+}
+
+func Test_RFC2548_MPPEKeys(t *testing.T) {
+	secret := []byte("xyzzy5461")
+	request := radius.New(radius.CodeAccessRequest, secret)
+	request.Add("User-Name", "nemo")
+
+	sendKey := bytes.Repeat([]byte{0xab}, 32)
+	recvKey := bytes.Repeat([]byte{0xcd}, 32)
+
+	response := radius.Packet{
+		Code:          radius.CodeAccessAccept,
+		Identifier:    request.Identifier,
+		Authenticator: request.Authenticator,
+		Secret:        secret,
+		Dictionary:    radius.Builtin,
+	}
+	if err := response.Add("MS-MPPE-Send-Key", sendKey); err != nil {
+		t.Fatal(err)
+	}
+	response.Add("MS-MPPE-Recv-Key", recvKey)
+
+	wire, err := response.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// vendor header (8 bytes), salt (2 bytes), encrypted key length and key
+	// padded to 48 bytes
+	if len(wire) != 20+2*(8+2+48) {
+		t.Fatal("unexpected packet length", len(wire))
+	}
+	if wire[20+8]&0x80 == 0 {
+		t.Fatal("expecting the most significant bit of the salt to be set")
+	}
+	if bytes.Contains(wire, sendKey[:16]) {
+		t.Fatal("expecting MS-MPPE-Send-Key to be encrypted")
+	}
+
+	received, err := radius.ParseResponse(wire, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !received.IsAuthentic(request) {
+		t.Fatal("expecting response to be authentic")
+	}
+	if key, _ := received.Value("MS-MPPE-Send-Key").([]byte); !bytes.Equal(key, sendKey) {
+		t.Fatalf("incorrect MS-MPPE-Send-Key % x", key)
+	}
+	if key, _ := received.Value("MS-MPPE-Recv-Key").([]byte); !bytes.Equal(key, recvKey) {
+		t.Fatalf("incorrect MS-MPPE-Recv-Key % x", key)
+	}
+}
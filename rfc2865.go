@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"crypto/md5"
 	"errors"
-	//"fmt"
 )
 
 func init() {
@@ -51,44 +50,91 @@ func init() {
 	Builtin.MustRegister("NAS-Port-Type", 61, AttributeInteger)
 	Builtin.MustRegister("Port-Limit", 62, AttributeInteger)
 	Builtin.MustRegister("Login-LAT-Port", 63, AttributeString)
-	
-	// FreeRADIUS specific
-	Builtin.MustRegister("Message-Authenticator", 80, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Statistics-Type", 127, AttributeInteger)
-	
-	Builtin.MustRegister("FreeRADIUS-Total-Access-Requests", 128, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Access-Accepts", 129, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Access-Rejects", 130, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Access-Challenges", 131, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Auth-Responses", 132, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Auth-Duplicate-Requests", 133, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Auth-Malformed-Requests", 134, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Auth-Invalid-Requests", 135, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Auth-Dropped-Requests", 136, AttributeString)
-	Builtin.MustRegister("FreeRADIUS-Total-Auth-Unknown-Types", 137, AttributeString)
+
+	Builtin.MustRegisterValue("Service-Type", "Login-User", 1)
+	Builtin.MustRegisterValue("Service-Type", "Framed-User", 2)
+	Builtin.MustRegisterValue("Service-Type", "Callback-Login-User", 3)
+	Builtin.MustRegisterValue("Service-Type", "Callback-Framed-User", 4)
+	Builtin.MustRegisterValue("Service-Type", "Outbound-User", 5)
+	Builtin.MustRegisterValue("Service-Type", "Administrative-User", 6)
+	Builtin.MustRegisterValue("Service-Type", "NAS-Prompt-User", 7)
+	Builtin.MustRegisterValue("Service-Type", "Authenticate-Only", 8)
+	Builtin.MustRegisterValue("Service-Type", "Callback-NAS-Prompt", 9)
+	Builtin.MustRegisterValue("Service-Type", "Call-Check", 10)
+	Builtin.MustRegisterValue("Service-Type", "Callback-Administrative", 11)
+	Builtin.MustRegisterValue("Framed-Protocol", "PPP", 1)
+	Builtin.MustRegisterValue("Framed-Protocol", "SLIP", 2)
+	Builtin.MustRegisterValue("Framed-Protocol", "ARAP", 3)
+	Builtin.MustRegisterValue("Framed-Protocol", "Gandalf-SLML", 4)
+	Builtin.MustRegisterValue("Framed-Protocol", "Xylogics-IPX-SLIP", 5)
+	Builtin.MustRegisterValue("Framed-Protocol", "X.75-Synchronous", 6)
+	Builtin.MustRegisterValue("Framed-Routing", "None", 0)
+	Builtin.MustRegisterValue("Framed-Routing", "Broadcast", 1)
+	Builtin.MustRegisterValue("Framed-Routing", "Listen", 2)
+	Builtin.MustRegisterValue("Framed-Routing", "Broadcast-Listen", 3)
+	Builtin.MustRegisterValue("Framed-Compression", "None", 0)
+	Builtin.MustRegisterValue("Framed-Compression", "Van-Jacobson-TCP-IP", 1)
+	Builtin.MustRegisterValue("Framed-Compression", "IPX-Header-Compression", 2)
+	Builtin.MustRegisterValue("Framed-Compression", "Stac-LZS", 3)
+	Builtin.MustRegisterValue("Login-Service", "Telnet", 0)
+	Builtin.MustRegisterValue("Login-Service", "Rlogin", 1)
+	Builtin.MustRegisterValue("Login-Service", "TCP-Clear", 2)
+	Builtin.MustRegisterValue("Login-Service", "PortMaster", 3)
+	Builtin.MustRegisterValue("Login-Service", "LAT", 4)
+	Builtin.MustRegisterValue("Login-Service", "X25-PAD", 5)
+	Builtin.MustRegisterValue("Login-Service", "X25-T3POS", 6)
+	Builtin.MustRegisterValue("Login-Service", "TCP-Clear-Quiet", 8)
+	Builtin.MustRegisterValue("Termination-Action", "Default", 0)
+	Builtin.MustRegisterValue("Termination-Action", "RADIUS-Request", 1)
+	Builtin.MustRegisterValue("NAS-Port-Type", "Async", 0)
+	Builtin.MustRegisterValue("NAS-Port-Type", "Sync", 1)
+	Builtin.MustRegisterValue("NAS-Port-Type", "ISDN", 2)
+	Builtin.MustRegisterValue("NAS-Port-Type", "ISDN-V120", 3)
+	Builtin.MustRegisterValue("NAS-Port-Type", "ISDN-V110", 4)
+	Builtin.MustRegisterValue("NAS-Port-Type", "Virtual", 5)
+	Builtin.MustRegisterValue("NAS-Port-Type", "PIAFS", 6)
+	Builtin.MustRegisterValue("NAS-Port-Type", "HDLC-Clear-Channel", 7)
+	Builtin.MustRegisterValue("NAS-Port-Type", "X.25", 8)
+	Builtin.MustRegisterValue("NAS-Port-Type", "X.75", 9)
+	Builtin.MustRegisterValue("NAS-Port-Type", "G.3-Fax", 10)
+	Builtin.MustRegisterValue("NAS-Port-Type", "SDSL", 11)
+	Builtin.MustRegisterValue("NAS-Port-Type", "ADSL-CAP", 12)
+	Builtin.MustRegisterValue("NAS-Port-Type", "ADSL-DMT", 13)
+	Builtin.MustRegisterValue("NAS-Port-Type", "IDSL", 14)
+	Builtin.MustRegisterValue("NAS-Port-Type", "Ethernet", 15)
+	Builtin.MustRegisterValue("NAS-Port-Type", "xDSL", 16)
+	Builtin.MustRegisterValue("NAS-Port-Type", "Cable", 17)
+	Builtin.MustRegisterValue("NAS-Port-Type", "Wireless-Other", 18)
+	Builtin.MustRegisterValue("NAS-Port-Type", "Wireless-802.11", 19)
 }
 
-// TODO: support User-Password attributes longer than 16 bytes
+// rfc2865UserPassword implements the User-Password hiding algorithm described
+// in RFC 2865 section 5.2. Passwords are padded with NULs to a multiple of 16
+// bytes and may be up to 128 bytes long.
 type rfc2865UserPassword struct{}
 
 func (rfc2865UserPassword) Decode(p *Packet, value []byte) (interface{}, error) {
 	if p.Secret == nil {
 		return nil, errors.New("radius: User-Password attribute requires Packet.Secret")
 	}
-	if len(value) != 16 {
+	if len(value) < 16 || len(value) > 128 || len(value)%16 != 0 {
 		return nil, errors.New("radius: invalid User-Password attribute length")
 	}
 	v := make([]byte, len(value))
-	copy(v, value)
 
 	var mask [md5.Size]byte
 	hash := md5.New()
-	hash.Write(p.Secret)
-	hash.Write(p.Authenticator[:])
-	hash.Sum(mask[0:0])
+	prev := p.Authenticator[:]
+	for i := 0; i < len(value); i += md5.Size {
+		hash.Reset()
+		hash.Write(p.Secret)
+		hash.Write(prev)
+		hash.Sum(mask[0:0])
 
-	for i, b := range v {
-		v[i] = b ^ mask[i]
+		for j := 0; j < md5.Size; j++ {
+			v[i+j] = value[i+j] ^ mask[j]
+		}
+		prev = value[i : i+md5.Size]
 	}
 
 	if i := bytes.IndexByte(v, 0); i > -1 {
@@ -112,19 +158,31 @@ func (rfc2865UserPassword) Encode(p *Packet, value interface{}) ([]byte, error)
 		password = bytePassword
 	}
 
-	if len(password) > 16 {
+	if len(password) > 128 {
 		return nil, errors.New("radius: invalid User-Password attribute length")
 	}
 
+	size := (len(password) + md5.Size - 1) / md5.Size * md5.Size
+	if size == 0 {
+		size = md5.Size
+	}
+	enc := make([]byte, size)
+	copy(enc, password)
+
 	var mask [md5.Size]byte
 	hash := md5.New()
-	hash.Write(p.Secret)
-	hash.Write(p.Authenticator[:])
-	hash.Sum(mask[0:0])
+	prev := p.Authenticator[:]
+	for i := 0; i < len(enc); i += md5.Size {
+		hash.Reset()
+		hash.Write(p.Secret)
+		hash.Write(prev)
+		hash.Sum(mask[0:0])
 
-	for i, b := range password {
-		mask[i] = b ^ mask[i]
+		for j := 0; j < md5.Size; j++ {
+			enc[i+j] ^= mask[j]
+		}
+		prev = enc[i : i+md5.Size]
 	}
 
-	return mask[:], nil
+	return enc, nil
 }
@@ -3,10 +3,14 @@ package radius
 import(
 //"fmt"
 )
+
+// attrAcctDelayTime is the type of the Acct-Delay-Time attribute.
+const attrAcctDelayTime byte = 41
+
 func init() {
 	builtinOnce.Do(initDictionary)
 	Builtin.MustRegister("Acct-Status-Type", 40, AttributeInteger)
-	Builtin.MustRegister("Acct-Delay-Time", 41, AttributeInteger)
+	Builtin.MustRegister("Acct-Delay-Time", attrAcctDelayTime, AttributeInteger)
 	Builtin.MustRegister("Acct-Input-Octets", 42, AttributeInteger)
 	Builtin.MustRegister("Acct-Output-Octets", 43, AttributeInteger)
 	Builtin.MustRegister("Acct-Session-Id", 44, AttributeText)
@@ -17,4 +21,31 @@ func init() {
 	Builtin.MustRegister("Acct-Terminate-Cause", 49, AttributeInteger)
 	Builtin.MustRegister("Acct-Multi-Session-Id", 50, AttributeText)
 	Builtin.MustRegister("Acct-Link-Count", 51, AttributeInteger)
+
+	Builtin.MustRegisterValue("Acct-Status-Type", "Start", 1)
+	Builtin.MustRegisterValue("Acct-Status-Type", "Stop", 2)
+	Builtin.MustRegisterValue("Acct-Status-Type", "Interim-Update", 3)
+	Builtin.MustRegisterValue("Acct-Status-Type", "Accounting-On", 7)
+	Builtin.MustRegisterValue("Acct-Status-Type", "Accounting-Off", 8)
+	Builtin.MustRegisterValue("Acct-Authentic", "RADIUS", 1)
+	Builtin.MustRegisterValue("Acct-Authentic", "Local", 2)
+	Builtin.MustRegisterValue("Acct-Authentic", "Remote", 3)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "User-Request", 1)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Lost-Carrier", 2)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Lost-Service", 3)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Idle-Timeout", 4)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Session-Timeout", 5)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Admin-Reset", 6)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Admin-Reboot", 7)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Port-Error", 8)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "NAS-Error", 9)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "NAS-Request", 10)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "NAS-Reboot", 11)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Port-Unneeded", 12)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Port-Preempted", 13)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Port-Suspended", 14)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Service-Unavailable", 15)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Callback", 16)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "User-Error", 17)
+	Builtin.MustRegisterValue("Acct-Terminate-Cause", "Host-Request", 18)
 }
@@ -0,0 +1,216 @@
+package radius
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tagged is the value of an attribute that carries a tag, as described in
+// RFC 2868 section 3. Tags group attributes that refer to the same tunnel; a
+// Tag of zero means the tag is unused.
+type Tagged struct {
+	Tag   byte
+	Value interface{}
+}
+
+// String returns the string representation of the tagged value.
+func (t Tagged) String() string {
+	return fmt.Sprintf("%d:%v", t.Tag, t.Value)
+}
+
+// The tagged attribute value formats that are defined in RFC 2868.
+var (
+	// Tagged with a uint32 value
+	AttributeTaggedInteger AttributeCodec
+	// Tagged with a string value
+	AttributeTaggedText AttributeCodec
+)
+
+func init() {
+	AttributeTaggedInteger = rfc2868TaggedInteger{}
+	AttributeTaggedText = rfc2868TaggedText{}
+
+	builtinOnce.Do(initDictionary)
+	Builtin.MustRegister("Tunnel-Type", 64, AttributeTaggedInteger)
+	Builtin.MustRegister("Tunnel-Medium-Type", 65, AttributeTaggedInteger)
+	Builtin.MustRegister("Tunnel-Client-Endpoint", 66, AttributeTaggedText)
+	Builtin.MustRegister("Tunnel-Server-Endpoint", 67, AttributeTaggedText)
+	Builtin.MustRegister("Tunnel-Password", 69, rfc2868TunnelPassword{})
+	Builtin.MustRegister("Tunnel-Private-Group-Id", 81, AttributeTaggedText)
+	Builtin.MustRegister("Tunnel-Assignment-Id", 82, AttributeTaggedText)
+	Builtin.MustRegister("Tunnel-Preference", 83, AttributeTaggedInteger)
+	Builtin.MustRegister("Tunnel-Client-Auth-Id", 90, AttributeTaggedText)
+	Builtin.MustRegister("Tunnel-Server-Auth-Id", 91, AttributeTaggedText)
+
+	Builtin.MustRegisterValue("Tunnel-Type", "PPTP", 1)
+	Builtin.MustRegisterValue("Tunnel-Type", "L2F", 2)
+	Builtin.MustRegisterValue("Tunnel-Type", "L2TP", 3)
+	Builtin.MustRegisterValue("Tunnel-Type", "ATMP", 4)
+	Builtin.MustRegisterValue("Tunnel-Type", "VTP", 5)
+	Builtin.MustRegisterValue("Tunnel-Type", "AH", 6)
+	Builtin.MustRegisterValue("Tunnel-Type", "IP", 7)
+	Builtin.MustRegisterValue("Tunnel-Type", "MIN-IP", 8)
+	Builtin.MustRegisterValue("Tunnel-Type", "ESP", 9)
+	Builtin.MustRegisterValue("Tunnel-Type", "GRE", 10)
+	Builtin.MustRegisterValue("Tunnel-Type", "DVS", 11)
+	Builtin.MustRegisterValue("Tunnel-Type", "IP-in-IP", 12)
+	Builtin.MustRegisterValue("Tunnel-Type", "VLAN", 13)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "IPv4", 1)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "IPv6", 2)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "NSAP", 3)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "HDLC", 4)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "BBN-1822", 5)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "IEEE-802", 6)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "E.163", 7)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "E.164", 8)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "F.69", 9)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "X.121", 10)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "IPX", 11)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "Appletalk", 12)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "DecNet-IV", 13)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "Banyan-Vines", 14)
+	Builtin.MustRegisterValue("Tunnel-Medium-Type", "E.164-NSAP", 15)
+}
+
+// tagged converts value to a Tagged, which has a tag of zero if value was
+// not already a Tagged.
+func tagged(value interface{}) (Tagged, error) {
+	var t Tagged
+	if v, ok := value.(Tagged); ok {
+		t = v
+	} else if v, ok := value.(*Tagged); ok && v != nil {
+		t = *v
+	} else {
+		t.Value = value
+	}
+	if t.Tag > 0x1F {
+		return t, errors.New("radius: attribute tag must be between 0x00 and 0x1F")
+	}
+	return t, nil
+}
+
+// rfc2868TaggedString returns the string representation of a tagged value,
+// without the tag.
+func rfc2868TaggedString(value interface{}) string {
+	t, err := tagged(value)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(t.Value)
+}
+
+type rfc2868TaggedInteger struct{}
+
+func (rfc2868TaggedInteger) Decode(p *Packet, value []byte) (interface{}, error) {
+	if len(value) != 4 {
+		return nil, errors.New("radius: tagged integer attribute has invalid size")
+	}
+	integer := uint32(value[1])<<16 | uint32(value[2])<<8 | uint32(value[3])
+	return Tagged{
+		Tag:   value[0],
+		Value: integer,
+	}, nil
+}
+
+func (rfc2868TaggedInteger) Encode(p *Packet, value interface{}) ([]byte, error) {
+	t, err := tagged(value)
+	if err != nil {
+		return nil, err
+	}
+	integer, ok := t.Value.(uint32)
+	if !ok {
+		return nil, errors.New("radius: tagged integer attribute must be uint32")
+	}
+	if integer > 0xFFFFFF {
+		return nil, errors.New("radius: tagged integer attribute must fit in 24 bits")
+	}
+	return []byte{t.Tag, byte(integer >> 16), byte(integer >> 8), byte(integer)}, nil
+}
+
+func (rfc2868TaggedInteger) Transform(value interface{}) (interface{}, error) {
+	return tagged(value)
+}
+
+func (rfc2868TaggedInteger) String(value interface{}) string {
+	return rfc2868TaggedString(value)
+}
+
+type rfc2868TaggedText struct{}
+
+func (rfc2868TaggedText) Decode(p *Packet, value []byte) (interface{}, error) {
+	var t Tagged
+	// The tag is optional; a first byte greater than 0x1F is part of the
+	// value.
+	if len(value) > 0 && value[0] <= 0x1F {
+		t.Tag = value[0]
+		value = value[1:]
+	}
+	decoded, err := AttributeText.Decode(p, value)
+	if err != nil {
+		return nil, err
+	}
+	t.Value = decoded
+	return t, nil
+}
+
+func (rfc2868TaggedText) Encode(p *Packet, value interface{}) ([]byte, error) {
+	t, err := tagged(value)
+	if err != nil {
+		return nil, err
+	}
+	text, err := AttributeText.Encode(p, t.Value)
+	if err != nil {
+		return nil, err
+	}
+	if t.Tag == 0 && (len(text) == 0 || text[0] > 0x1F) {
+		return text, nil
+	}
+	return append([]byte{t.Tag}, text...), nil
+}
+
+func (rfc2868TaggedText) Transform(value interface{}) (interface{}, error) {
+	return tagged(value)
+}
+
+func (rfc2868TaggedText) String(value interface{}) string {
+	return rfc2868TaggedString(value)
+}
+
+// rfc2868TunnelPassword implements the Tunnel-Password attribute (RFC 2868
+// section 3.5): a tag followed by the password, salt encrypted in the same
+// way as MS-MPPE-Send-Key.
+type rfc2868TunnelPassword struct{}
+
+func (rfc2868TunnelPassword) Decode(p *Packet, value []byte) (interface{}, error) {
+	if len(value) < 1 {
+		return nil, errors.New("radius: invalid Tunnel-Password attribute length")
+	}
+	password, err := rfc2548SaltEncrypted{}.Decode(p, value[1:])
+	if err != nil {
+		return nil, err
+	}
+	return Tagged{
+		Tag:   value[0],
+		Value: string(password.([]byte)),
+	}, nil
+}
+
+func (rfc2868TunnelPassword) Encode(p *Packet, value interface{}) ([]byte, error) {
+	t, err := tagged(value)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := rfc2548SaltEncrypted{}.Encode(p, t.Value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{t.Tag}, encrypted...), nil
+}
+
+func (rfc2868TunnelPassword) Transform(value interface{}) (interface{}, error) {
+	return tagged(value)
+}
+
+func (rfc2868TunnelPassword) String(value interface{}) string {
+	return rfc2868TaggedString(value)
+}
@@ -0,0 +1,115 @@
+package radius
+
+import (
+	"errors"
+	"net"
+)
+
+// The IPv6 attribute value formats that are defined in RFC 3162.
+var (
+	// net.IP
+	AttributeIPv6Address AttributeCodec
+	// *net.IPNet
+	AttributeIPv6Prefix AttributeCodec
+	// []byte (8 bytes)
+	AttributeInterfaceID AttributeCodec
+)
+
+func init() {
+	AttributeIPv6Address = attributeIPv6Address{}
+	AttributeIPv6Prefix = attributeIPv6Prefix{}
+	AttributeInterfaceID = attributeInterfaceID{}
+
+	builtinOnce.Do(initDictionary)
+	Builtin.MustRegister("NAS-IPv6-Address", 95, AttributeIPv6Address)
+	Builtin.MustRegister("Framed-Interface-Id", 96, AttributeInterfaceID)
+	Builtin.MustRegister("Framed-IPv6-Prefix", 97, AttributeIPv6Prefix)
+	Builtin.MustRegister("Login-IPv6-Host", 98, AttributeIPv6Address)
+	Builtin.MustRegister("Framed-IPv6-Route", 99, AttributeText)
+	Builtin.MustRegister("Framed-IPv6-Pool", 100, AttributeText)
+}
+
+type attributeIPv6Address struct{}
+
+func (attributeIPv6Address) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != net.IPv6len {
+		return nil, errors.New("radius: IPv6 address attribute has invalid size")
+	}
+	v := make([]byte, len(value))
+	copy(v, value)
+	return net.IP(v), nil
+}
+
+func (attributeIPv6Address) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	ip, ok := value.(net.IP)
+	if !ok {
+		return nil, errors.New("radius: IPv6 address attribute must be net.IP")
+	}
+	// IPv4-mapped IPv6 addresses are valid values; only their 16 byte
+	// form is accepted.
+	if len(ip) != net.IPv6len {
+		return nil, errors.New("radius: IPv6 address attribute must be a 16 byte net.IP")
+	}
+	return []byte(ip), nil
+}
+
+type attributeIPv6Prefix struct{}
+
+func (attributeIPv6Prefix) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) < 2 || len(value) > 2+net.IPv6len {
+		return nil, errors.New("radius: IPv6 prefix attribute has invalid size")
+	}
+	bits := int(value[1])
+	if bits > 128 || (bits+7)/8 > len(value)-2 {
+		return nil, errors.New("radius: IPv6 prefix attribute has invalid prefix length")
+	}
+	mask := net.CIDRMask(bits, 128)
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, value[2:])
+	return &net.IPNet{
+		IP:   ip.Mask(mask),
+		Mask: mask,
+	}, nil
+}
+
+func (attributeIPv6Prefix) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	var prefix *net.IPNet
+	switch v := value.(type) {
+	case *net.IPNet:
+		prefix = v
+	case net.IPNet:
+		prefix = &v
+	}
+	if prefix == nil {
+		return nil, errors.New("radius: IPv6 prefix attribute must be *net.IPNet")
+	}
+	bits, size := prefix.Mask.Size()
+	if size != 128 || len(prefix.IP) != net.IPv6len {
+		return nil, errors.New("radius: IPv6 prefix attribute must be an IPv6 *net.IPNet")
+	}
+	ip := prefix.IP.Mask(prefix.Mask)
+	raw := []byte{0, byte(bits)}
+	return append(raw, ip[:(bits+7)/8]...), nil
+}
+
+type attributeInterfaceID struct{}
+
+func (attributeInterfaceID) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != 8 {
+		return nil, errors.New("radius: interface id attribute has invalid size")
+	}
+	v := make([]byte, len(value))
+	copy(v, value)
+	return v, nil
+}
+
+func (attributeInterfaceID) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	raw, ok := value.([]byte)
+	if !ok {
+		return nil, errors.New("radius: interface id attribute must be []byte")
+	}
+	if len(raw) != 8 {
+		return nil, errors.New("radius: interface id attribute must be 8 bytes long")
+	}
+	return raw, nil
+}
@@ -0,0 +1,128 @@
+package radius
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"errors"
+)
+
+// attrMessageAuthenticator is the type of the Message-Authenticator attribute.
+const attrMessageAuthenticator byte = 80
+
+func init() {
+	builtinOnce.Do(initDictionary)
+	Builtin.MustRegister("EAP-Message", 79, AttributeString)
+	if err := Builtin.SetConcat("EAP-Message", true); err != nil {
+		panic(err)
+	}
+	Builtin.MustRegister("Message-Authenticator", attrMessageAuthenticator, rfc3579MessageAuthenticator{})
+}
+
+// rfc3579MessageAuthenticator is the codec of the Message-Authenticator
+// attribute. The attribute is always encoded as 16 zero bytes; Packet.Encode
+// replaces them with the HMAC-MD5 of the packet once it has been laid out.
+type rfc3579MessageAuthenticator struct{}
+
+func (rfc3579MessageAuthenticator) Decode(p *Packet, value []byte) (interface{}, error) {
+	if len(value) != md5.Size {
+		return nil, errors.New("radius: invalid Message-Authenticator attribute length")
+	}
+	v := make([]byte, len(value))
+	copy(v, value)
+	return v, nil
+}
+
+func (rfc3579MessageAuthenticator) Encode(p *Packet, value interface{}) ([]byte, error) {
+	return make([]byte, md5.Size), nil
+}
+
+// messageAuthenticatorAttr returns the packet's Message-Authenticator
+// attribute, or nil if it does not have one.
+func (p *Packet) messageAuthenticatorAttr() *Attribute {
+	for _, attr := range p.Attributes {
+		if attr.Vendor == 0 && attr.Type == attrMessageAuthenticator {
+			return attr
+		}
+	}
+	return nil
+}
+
+// messageAuthenticator calculates the Message-Authenticator of a packet, as
+// described in RFC 3579 section 3.2. attrs must be the encoded attributes of
+// the packet with the Message-Authenticator value set to zero.
+func messageAuthenticator(code Code, identifier byte, authenticator, attrs, secret []byte) []byte {
+	length := 20 + len(attrs)
+	mac := hmac.New(md5.New, secret)
+	mac.Write([]byte{byte(code), identifier, byte(length >> 8), byte(length)})
+	mac.Write(authenticator)
+	mac.Write(attrs)
+	return mac.Sum(nil)
+}
+
+// IsMessageAuthentic returns if the packet contains a valid
+// Message-Authenticator attribute. When p is a response, request must be the
+// packet it is responding to; otherwise request is ignored and can be nil. If
+// p was returned by Parse, the packet is verified as it was received.
+//
+// false is returned if the packet does not have a Message-Authenticator
+// attribute.
+func (p *Packet) IsMessageAuthentic(request *Packet) bool {
+	attr := p.messageAuthenticatorAttr()
+	if attr == nil {
+		return false
+	}
+	value, ok := attr.Value.([]byte)
+	if !ok {
+		return false
+	}
+
+	var authenticator []byte
+	switch {
+	case p.Code == CodeAccessRequest || p.Code == CodeStatusServer:
+		authenticator = p.Authenticator[:]
+	case p.Code.hasZeroAuthenticator():
+		authenticator = make([]byte, 16)
+	case p.Code.isResponse():
+		if request == nil {
+			return false
+		}
+		authenticator = request.Authenticator[:]
+	default:
+		return false
+	}
+
+	var attrs []byte
+	if p.raw != nil {
+		msgAuth := findAttribute(p.raw[20:], attrMessageAuthenticator)
+		if msgAuth < 0 || msgAuth+md5.Size > len(p.raw)-20 {
+			return false
+		}
+		attrs = make([]byte, len(p.raw)-20)
+		copy(attrs, p.raw[20:])
+		copy(attrs[msgAuth:msgAuth+md5.Size], make([]byte, md5.Size))
+	} else {
+		var err error
+		attrs, _, err = p.encodeAttributes()
+		if err != nil {
+			return false
+		}
+	}
+	expected := messageAuthenticator(p.Code, p.Identifier, authenticator, attrs, p.Secret)
+	return hmac.Equal(expected, value)
+}
+
+// findAttribute returns the offset of the value of the first attribute of the
+// given type in the encoded attributes, or -1 if there is no such attribute.
+func findAttribute(attrs []byte, t byte) int {
+	for offset := 0; offset+2 <= len(attrs); {
+		length := int(attrs[offset+1])
+		if length < 2 {
+			return -1
+		}
+		if attrs[offset] == t {
+			return offset + 2
+		}
+		offset += length
+	}
+	return -1
+}
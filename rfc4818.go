@@ -0,0 +1,6 @@
+package radius
+
+func init() {
+	builtinOnce.Do(initDictionary)
+	Builtin.MustRegister("Delegated-IPv6-Prefix", 123, AttributeIPv6Prefix)
+}
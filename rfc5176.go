@@ -0,0 +1,26 @@
+package radius
+
+func init() {
+	builtinOnce.Do(initDictionary)
+	Builtin.MustRegister("Error-Cause", 101, AttributeInteger)
+
+	Builtin.MustRegisterValue("Service-Type", "Authorize-Only", 17)
+
+	Builtin.MustRegisterValue("Error-Cause", "Residual-Session-Context-Removed", 201)
+	Builtin.MustRegisterValue("Error-Cause", "Invalid-EAP-Packet", 202)
+	Builtin.MustRegisterValue("Error-Cause", "Unsupported-Attribute", 401)
+	Builtin.MustRegisterValue("Error-Cause", "Missing-Attribute", 402)
+	Builtin.MustRegisterValue("Error-Cause", "NAS-Identification-Mismatch", 403)
+	Builtin.MustRegisterValue("Error-Cause", "Invalid-Request", 404)
+	Builtin.MustRegisterValue("Error-Cause", "Unsupported-Service", 405)
+	Builtin.MustRegisterValue("Error-Cause", "Unsupported-Extension", 406)
+	Builtin.MustRegisterValue("Error-Cause", "Invalid-Attribute-Value", 407)
+	Builtin.MustRegisterValue("Error-Cause", "Administratively-Prohibited", 501)
+	Builtin.MustRegisterValue("Error-Cause", "Request-Not-Routable", 502)
+	Builtin.MustRegisterValue("Error-Cause", "Session-Context-Not-Found", 503)
+	Builtin.MustRegisterValue("Error-Cause", "Session-Context-Not-Removable", 504)
+	Builtin.MustRegisterValue("Error-Cause", "Other-Proxy-Processing-Error", 505)
+	Builtin.MustRegisterValue("Error-Cause", "Resources-Unavailable", 506)
+	Builtin.MustRegisterValue("Error-Cause", "Request-Initiated", 507)
+	Builtin.MustRegisterValue("Error-Cause", "Multiple-Session-Selection-Unsupported", 508)
+}
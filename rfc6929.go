@@ -0,0 +1,201 @@
+package radius
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// The attribute value formats that are defined in RFC 6929, and the
+// ipv4prefix format defined in RFC 6572.
+var (
+	// uint64
+	AttributeInteger64 AttributeCodec
+	// *net.IPNet
+	AttributeIPv4Prefix AttributeCodec
+	// []*Attribute, whose values are []byte
+	AttributeTLV AttributeCodec
+)
+
+func init() {
+	AttributeInteger64 = attributeInteger64{}
+	AttributeIPv4Prefix = attributeIPv4Prefix{}
+	AttributeTLV = attributeTLV{}
+}
+
+// isExtendedType returns if t is one of the Extended Type (241 to 244) or
+// Long Extended Type (245 and 246) attributes of RFC 6929.
+func isExtendedType(t byte) bool {
+	return t >= 241 && t <= 246
+}
+
+// isLongExtendedType returns if t is a Long Extended Type attribute.
+func isLongExtendedType(t byte) bool {
+	return t == 245 || t == 246
+}
+
+// the More flag of Long Extended Type attributes
+const extendedFlagMore = 0x80
+
+// parseExtended sets the Extended-Type of attr from value, the value of an
+// extended attribute, and returns its data. If attr is a fragmented Long
+// Extended Type attribute, the following fragments are consumed from next
+// and reassembled (RFC 6929 section 2.2).
+func parseExtended(attr *Attribute, value, next []byte) ([]byte, []byte, error) {
+	if len(value) < 1 {
+		return nil, nil, errors.New("radius: extended attribute must be at least 3 bytes long")
+	}
+	attr.ExtendedType = value[0]
+	if !isLongExtendedType(attr.Type) {
+		return value[1:], next, nil
+	}
+
+	if len(value) < 2 {
+		return nil, nil, errors.New("radius: long extended attribute must be at least 4 bytes long")
+	}
+	if value[1]&extendedFlagMore == 0 {
+		return value[2:], next, nil
+	}
+	data := append([]byte(nil), value[2:]...)
+	for more := true; more; {
+		if len(next) < 4 || next[0] != attr.Type || next[1] < 4 || int(next[1]) > len(next) || next[2] != attr.ExtendedType {
+			return nil, nil, errors.New("radius: invalid long extended attribute fragment")
+		}
+		more = next[3]&extendedFlagMore != 0
+		data = append(data, next[4:next[1]]...)
+		next = next[next[1]:]
+	}
+	return data, next, nil
+}
+
+// encodeExtended writes the extended attribute attr, whose encoded value is
+// value, to buffer. Long Extended Type attributes are fragmented if needed.
+func encodeExtended(buffer *bytes.Buffer, attr *Attribute, value []byte) error {
+	if !isLongExtendedType(attr.Type) {
+		if len(value) > 252 {
+			return errors.New("radius: encoded attribute is too long")
+		}
+		buffer.WriteByte(attr.Type)
+		buffer.WriteByte(byte(len(value) + 3))
+		buffer.WriteByte(attr.ExtendedType)
+		buffer.Write(value)
+		return nil
+	}
+
+	for first := true; first || len(value) > 0; first = false {
+		fragment := value
+		var flags byte
+		if len(fragment) > 251 {
+			fragment = fragment[:251]
+			flags |= extendedFlagMore
+		}
+		buffer.WriteByte(attr.Type)
+		buffer.WriteByte(byte(len(fragment) + 4))
+		buffer.WriteByte(attr.ExtendedType)
+		buffer.WriteByte(flags)
+		buffer.Write(fragment)
+		value = value[len(fragment):]
+	}
+	return nil
+}
+
+type attributeInteger64 struct{}
+
+func (attributeInteger64) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != 8 {
+		return nil, errors.New("radius: integer64 attribute has invalid size")
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+func (attributeInteger64) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	integer, ok := value.(uint64)
+	if !ok {
+		return nil, errors.New("radius: integer64 attribute must be uint64")
+	}
+	raw := make([]byte, 8)
+	binary.BigEndian.PutUint64(raw, integer)
+	return raw, nil
+}
+
+type attributeIPv4Prefix struct{}
+
+func (attributeIPv4Prefix) Decode(packet *Packet, value []byte) (interface{}, error) {
+	if len(value) != 2+net.IPv4len {
+		return nil, errors.New("radius: IPv4 prefix attribute has invalid size")
+	}
+	bits := int(value[1] & 0x3f)
+	if bits > 32 {
+		return nil, errors.New("radius: IPv4 prefix attribute has invalid prefix length")
+	}
+	mask := net.CIDRMask(bits, 32)
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, value[2:])
+	return &net.IPNet{
+		IP:   ip.Mask(mask),
+		Mask: mask,
+	}, nil
+}
+
+func (attributeIPv4Prefix) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	var prefix *net.IPNet
+	switch v := value.(type) {
+	case *net.IPNet:
+		prefix = v
+	case net.IPNet:
+		prefix = &v
+	}
+	if prefix == nil {
+		return nil, errors.New("radius: IPv4 prefix attribute must be *net.IPNet")
+	}
+	bits, size := prefix.Mask.Size()
+	ip := prefix.IP.To4()
+	if size != 32 || ip == nil {
+		return nil, errors.New("radius: IPv4 prefix attribute must be an IPv4 *net.IPNet")
+	}
+	raw := []byte{0, byte(bits)}
+	return append(raw, ip.Mask(prefix.Mask)...), nil
+}
+
+// attributeTLV splits a tlv value into its nested attributes. The nested
+// values are not decoded.
+type attributeTLV struct{}
+
+func (attributeTLV) Decode(packet *Packet, value []byte) (interface{}, error) {
+	var tlvs []*Attribute
+	for len(value) > 0 {
+		if len(value) < 2 || value[1] < 2 || int(value[1]) > len(value) {
+			return nil, errors.New("radius: tlv attribute has invalid size")
+		}
+		v := make([]byte, value[1]-2)
+		copy(v, value[2:value[1]])
+		tlvs = append(tlvs, &Attribute{
+			Type:  value[0],
+			Value: v,
+		})
+		value = value[value[1]:]
+	}
+	return tlvs, nil
+}
+
+func (attributeTLV) Encode(packet *Packet, value interface{}) ([]byte, error) {
+	tlvs, ok := value.([]*Attribute)
+	if !ok {
+		return nil, errors.New("radius: tlv attribute must be []*Attribute")
+	}
+	var buffer bytes.Buffer
+	for _, tlv := range tlvs {
+		raw, err := AttributeString.Encode(packet, tlv.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) > 253 {
+			return nil, errors.New("radius: encoded tlv is too long")
+		}
+		buffer.WriteByte(tlv.Type)
+		buffer.WriteByte(byte(len(raw) + 2))
+		buffer.Write(raw)
+	}
+	return buffer.Bytes(), nil
+}
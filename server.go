@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"sync"
+	"time"
 )
 
 // Handler is a value that can handle a server's RADIUS packet event.
@@ -47,6 +48,15 @@ type ResponseWriter interface {
 	AccessChallenge(attributes ...*Attribute) error
 
 	AccountingResponse(attributes ...*Attribute) error
+
+	// DisconnectACK and DisconnectNAK acknowledge or reject a
+	// Disconnect-Request, and CoAACK and CoANAK a CoA-Request. The given
+	// attributes are included in the response; a NAK should include an
+	// Error-Cause attribute.
+	DisconnectACK(attributes ...*Attribute) error
+	DisconnectNAK(attributes ...*Attribute) error
+	CoAACK(attributes ...*Attribute) error
+	CoANAK(attributes ...*Attribute) error
 }
 
 type responseWriter struct {
@@ -56,6 +66,10 @@ type responseWriter struct {
 	addr *net.UDPAddr
 	// original packet
 	packet *Packet
+	// server and IP address of the client, for counting responses
+	server    *Server
+	client    string
+	responded bool
 }
 
 func (r *responseWriter) LocalAddr() net.Addr {
@@ -67,6 +81,21 @@ func (r *responseWriter) RemoteAddr() net.Addr {
 }
 
 func (r *responseWriter) accessRespond(code Code, attributes ...*Attribute) error {
+	// RFC 3579 section 3.2: responses to packets carrying a
+	// Message-Authenticator carry one as well.
+	if r.packet.messageAuthenticatorAttr() != nil {
+		hasMsgAuth := false
+		for _, attr := range attributes {
+			if attr.Vendor == 0 && attr.Type == attrMessageAuthenticator {
+				hasMsgAuth = true
+				break
+			}
+		}
+		if !hasMsgAuth {
+			attributes = append(attributes, &Attribute{Type: attrMessageAuthenticator})
+		}
+	}
+
 	packet := Packet{
 		Code:          code,
 		Identifier:    r.packet.Identifier,
@@ -101,6 +130,22 @@ func (r *responseWriter) AccountingResponse(attributes ...*Attribute) error {
 	return r.accessRespond(CodeAccountingResponse, attributes...)
 }
 
+func (r *responseWriter) DisconnectACK(attributes ...*Attribute) error {
+	return r.accessRespond(CodeDisconnectACK, attributes...)
+}
+
+func (r *responseWriter) DisconnectNAK(attributes ...*Attribute) error {
+	return r.accessRespond(CodeDisconnectNAK, attributes...)
+}
+
+func (r *responseWriter) CoAACK(attributes ...*Attribute) error {
+	return r.accessRespond(CodeCoAACK, attributes...)
+}
+
+func (r *responseWriter) CoANAK(attributes ...*Attribute) error {
+	return r.accessRespond(CodeCoANAK, attributes...)
+}
+
 func (r *responseWriter) Write(packet *Packet) error {
 	raw, err := packet.Encode()
 	if err != nil {
@@ -109,12 +154,18 @@ func (r *responseWriter) Write(packet *Packet) error {
 	if _, err := r.conn.WriteToUDP(raw, r.addr); err != nil {
 		return err
 	}
+	r.responded = true
+	if r.server != nil && r.packet.Code != CodeStatusServer {
+		r.server.countResponse(r.client, packet.Code)
+	}
 	return nil
 }
 
 // Server is a server that listens for and handles RADIUS packets.
 type Server struct {
 	// Address to bind the server on. If empty, the address defaults to ":1812".
+	// A Dynamic Authorization Server, which receives Disconnect-Request and
+	// CoA-Request packets, usually listens on DynamicAuthorizationPort.
 	Addr string
 
 	// Network of the server. Valid values are "udp", "udp4", "udp6". If empty,
@@ -130,7 +181,38 @@ type Server struct {
 	ClientNets    []net.IPNet
 	ClientSecrets []string
 
-	// Dictionary used when decoding incoming packets.
+	// If true, Access-Request packets without a Message-Authenticator
+	// attribute are dropped. Packets with an invalid Message-Authenticator
+	// are always dropped.
+	RequireMessageAuthenticator bool
+
+	// If not zero, Status-Server packets (RFC 5997) are answered by the
+	// server instead of being passed to Handler, with a response of the
+	// given code: CodeAccessAccept for an authentication server, or
+	// CodeAccountingResponse for an accounting server. Status-Server
+	// packets without a Message-Authenticator are always dropped.
+	//
+	// If the Status-Server packet has a FreeRADIUS-Statistics-Type
+	// attribute, the response carries the server's counters (see Stats) in
+	// the FreeRADIUS-Total-* attributes, as FreeRADIUS does. With the
+	// Client statistics type, the counters are those of the client given in
+	// FreeRADIUS-Stats-Client-IP-Address. The Dictionary must have the
+	// FreeRADIUS attributes registered, as Builtin does.
+	StatusServerResponse Code
+
+	// If true, requests are checked with Packet.Validate and the violations
+	// of invalid requests are logged. If DropInvalidRequests is also true,
+	// invalid requests are dropped instead of being handled.
+	ValidateRequests    bool
+	DropInvalidRequests bool
+
+	// If true, requests are parsed with ParseLenient, and the errors of
+	// their undecodable attributes are logged instead of dropping them.
+	LenientParsing bool
+
+	// Dictionary used when decoding incoming packets. If nil, Builtin is
+	// used. A snapshot (see Dictionary.Snapshot) is looked up without
+	// locking, and is recommended for busy servers.
 	Dictionary *Dictionary
 
 	// The packet handler that handles incoming, valid packets.
@@ -138,6 +220,9 @@ type Server struct {
 
 	// Listener
 	listener *net.UDPConn
+
+	// counters, see Stats
+	stats serverStats
 }
 
 func (s *Server) ResetClientNets() error {
@@ -222,6 +307,10 @@ func (s *Server) ListenAndServe() error {
 		}
 	}
 
+	s.stats.mu.Lock()
+	s.stats.start = time.Now()
+	s.stats.mu.Unlock()
+
 	type activeKey struct {
 		IP         string
 		Identifier byte
@@ -261,8 +350,10 @@ func (s *Server) ListenAndServe() error {
 
 			if legal == false {
 				log.Println(remoteAddr.IP, " inlegal")
+				s.count("", Code(buff[0]), countInvalidRequests)
 				return
 			}
+			client := remoteAddr.IP.String()
 
 			if s.ClientNets != nil {
 				log.Println(remoteAddr.IP)
@@ -274,11 +365,58 @@ func (s *Server) ListenAndServe() error {
 				}
 			}
 
-			packet, err := Parse(buff, secret, s.Dictionary)
+			parse := Parse
+			if s.LenientParsing {
+				parse = ParseLenient
+			}
+			dictionary := s.Dictionary
+			if dictionary == nil {
+				dictionary = Builtin
+			}
+			packet, err := parse(buff, secret, dictionary)
 			if err != nil {
+				s.count(client, Code(buff[0]), countMalformedRequests)
+				return
+			}
+			for _, err := range packet.Errors {
+				log.Println(remoteAddr.IP, err)
+			}
+
+			// The Request Authenticator of dynamic authorization
+			// requests is calculated with the secret (RFC 5176 section
+			// 3.5).
+			if (packet.Code == CodeDisconnectRequest || packet.Code == CodeCoARequest) && !packet.IsAuthentic(packet) {
+				log.Println(remoteAddr.IP, " invalid Request Authenticator")
+				s.count(client, packet.Code, countInvalidRequests)
+				return
+			}
+
+			if packet.messageAuthenticatorAttr() != nil {
+				if !packet.IsMessageAuthentic(nil) {
+					log.Println(remoteAddr.IP, " invalid Message-Authenticator")
+					s.count(client, packet.Code, countInvalidRequests)
+					return
+				}
+			} else if packet.Code == CodeStatusServer {
+				log.Println(remoteAddr.IP, " missing Message-Authenticator")
+				s.count(client, packet.Code, countInvalidRequests)
+				return
+			} else if s.RequireMessageAuthenticator && packet.Code == CodeAccessRequest {
+				log.Println(remoteAddr.IP, " missing Message-Authenticator")
+				s.count(client, packet.Code, countDroppedRequests)
 				return
 			}
 
+			if s.ValidateRequests {
+				if err := packet.Validate(); err != nil {
+					log.Println(remoteAddr.IP, err)
+					if s.DropInvalidRequests {
+						s.count(client, packet.Code, countDroppedRequests)
+						return
+					}
+				}
+			}
+
 			key := activeKey{
 				IP:         remoteAddr.String(),
 				Identifier: packet.Identifier,
@@ -287,6 +425,7 @@ func (s *Server) ListenAndServe() error {
 			activeLock.Lock()
 			if _, ok := active[key]; ok {
 				activeLock.Unlock()
+				s.count(client, packet.Code, countDuplicateRequests)
 				return
 			}
 			active[key] = true
@@ -296,9 +435,26 @@ func (s *Server) ListenAndServe() error {
 				conn:   conn,
 				addr:   remoteAddr,
 				packet: packet,
+				server: s,
+				client: client,
 			}
 
-			s.Handler.ServeRadius(&response, packet)
+			if packet.Code == CodeStatusServer && s.StatusServerResponse != 0 {
+				if err := response.accessRespond(s.StatusServerResponse, s.statisticsAttributes(packet)...); err != nil {
+					log.Println(remoteAddr.IP, err)
+				}
+			} else {
+				switch {
+				case !packet.Code.isRequest():
+					s.count(client, packet.Code, countUnknownTypes)
+				case packet.Code != CodeStatusServer:
+					s.count(client, packet.Code, countRequests)
+				}
+				s.Handler.ServeRadius(&response, packet)
+				if !response.responded && packet.Code.isRequest() && packet.Code != CodeStatusServer {
+					s.count(client, packet.Code, countDroppedRequests)
+				}
+			}
 
 			activeLock.Lock()
 			delete(active, key)
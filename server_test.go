@@ -0,0 +1,230 @@
+package radius_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/runner-mei/radius"
+)
+
+// startServer starts s on a free loopback port, accepting packets from
+// 127.0.0.1 with the given secret, and returns its address.
+func startServer(t *testing.T, s *radius.Server, secret string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+
+	s.Addr = addr
+	s.ClientsMap = map[string]string{"127.0.0.1": secret}
+	go s.ListenAndServe()
+	t.Cleanup(func() {
+		s.Close()
+	})
+	// wait for the server to listen
+	time.Sleep(50 * time.Millisecond)
+	return addr
+}
+
+func Test_DynamicAuthorization(t *testing.T) {
+	secret := []byte("dae-secret")
+	server := radius.Server{
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, p *radius.Packet) {
+			switch p.Code {
+			case radius.CodeDisconnectRequest:
+				w.DisconnectACK()
+			case radius.CodeCoARequest:
+				w.CoANAK(p.Dictionary.MustAttr("Error-Cause", "Session-Context-Not-Found"))
+			case radius.CodeAccountingRequest:
+				w.AccountingResponse()
+			}
+		}),
+	}
+	addr := startServer(t, &server, string(secret))
+
+	client := radius.Client{ReadTimeout: 2 * time.Second}
+
+	request := radius.New(radius.CodeDisconnectRequest, secret)
+	request.Add("Acct-Session-Id", "0001")
+	request.Add("Message-Authenticator", nil)
+	response, err := client.ExchangeDynamicAuthorization(request, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != radius.CodeDisconnectACK {
+		t.Fatalf("expecting Disconnect-ACK, got %v", response.Code)
+	}
+	// Exchange only returns responses with a valid Message-Authenticator.
+	if response.Attr("Message-Authenticator") == nil {
+		t.Fatal("expecting the response to have a Message-Authenticator")
+	}
+
+	request = radius.New(radius.CodeCoARequest, secret)
+	request.Add("Acct-Session-Id", "0002")
+	request.Add("Session-Timeout", uint32(60))
+	response, err = client.ExchangeDynamicAuthorization(request, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != radius.CodeCoANAK {
+		t.Fatalf("expecting CoA-NAK, got %v", response.Code)
+	}
+	if cause := response.String("Error-Cause"); cause != "Session-Context-Not-Found" {
+		t.Fatalf("expecting Error-Cause Session-Context-Not-Found, got %q", cause)
+	}
+
+	// Requests with an invalid Request Authenticator are dropped.
+	request = radius.New(radius.CodeCoARequest, []byte("wrong"))
+	request.Add("Acct-Session-Id", "0003")
+	client.ReadTimeout = 200 * time.Millisecond
+	if _, err := client.ExchangeDynamicAuthorization(request, addr); err == nil {
+		t.Fatal("expecting a request with the wrong secret to be dropped")
+	}
+
+	// The Request Authenticator of Accounting-Request packets is not
+	// checked by the server.
+	accounting := radius.New(radius.CodeAccountingRequest, []byte("wrong"))
+	accounting.Add("Acct-Status-Type", "Start")
+	wire, err := accounting.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write(wire); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("expecting an Accounting-Response, got %v", err)
+	}
+	if n < 20 || radius.Code(buf[0]) != radius.CodeAccountingResponse {
+		t.Fatalf("expecting an Accounting-Response, got %v", buf[:n])
+	}
+
+	if _, err := client.ExchangeDynamicAuthorization(radius.New(radius.CodeAccessRequest, secret), addr); err == nil {
+		t.Fatal("expecting ExchangeDynamicAuthorization to reject an Access-Request")
+	}
+}
+
+func Test_StatusServer(t *testing.T) {
+	secret := []byte("status-secret")
+	handled := make(chan radius.Code, 1)
+	server := radius.Server{
+		StatusServerResponse: radius.CodeAccessAccept,
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, p *radius.Packet) {
+			handled <- p.Code
+			w.AccessReject()
+		}),
+	}
+	addr := startServer(t, &server, string(secret))
+
+	client := radius.Client{ReadTimeout: 2 * time.Second}
+	response, err := client.Ping(addr, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != radius.CodeAccessAccept {
+		t.Fatalf("expecting Access-Accept, got %v", response.Code)
+	}
+	select {
+	case code := <-handled:
+		t.Fatalf("expecting Status-Server to be answered by the server, Handler got %v", code)
+	default:
+	}
+
+	// Status-Server packets are encoded with a Message-Authenticator.
+	wire, err := radius.New(radius.CodeStatusServer, secret).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := radius.Parse(wire, secret, radius.Builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsMessageAuthentic(nil) {
+		t.Fatal("expecting a valid Message-Authenticator in Status-Server")
+	}
+}
+
+func Test_ServerStats(t *testing.T) {
+	secret := []byte("stats-secret")
+	server := radius.Server{
+		StatusServerResponse: radius.CodeAccessAccept,
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, p *radius.Packet) {
+			switch {
+			case p.Code == radius.CodeAccountingRequest:
+				w.AccountingResponse()
+			case p.String("User-Name") == "accept":
+				w.AccessAccept()
+			case p.String("User-Name") == "reject":
+				w.AccessReject()
+			}
+		}),
+	}
+	addr := startServer(t, &server, string(secret))
+	client := radius.Client{ReadTimeout: 2 * time.Second}
+
+	for _, name := range []string{"accept", "accept", "reject"} {
+		request := radius.New(radius.CodeAccessRequest, secret)
+		request.Add("User-Name", name)
+		if _, err := client.Exchange(request, addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	request := radius.New(radius.CodeAccountingRequest, secret)
+	request.Add("Acct-Status-Type", "Start")
+	if _, err := client.Exchange(request, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte{byte(radius.CodeAccessRequest), 1, 0, 5})
+	conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	expected := radius.Stats{
+		Auth: radius.Counters{Requests: 3, Responses: 3, Accepts: 2, Rejects: 1, MalformedRequests: 1},
+		Acct: radius.Counters{Requests: 1, Responses: 1},
+	}
+	if stats := server.Stats(); stats != expected {
+		t.Fatalf("expecting %+v, got %+v", expected, stats)
+	}
+	if stats, ok := server.ClientStats(net.IPv4(127, 0, 0, 1)); !ok || stats != expected {
+		t.Fatalf("expecting client stats %+v, got %+v", expected, stats)
+	}
+
+	status := radius.New(radius.CodeStatusServer, secret)
+	status.Add("FreeRADIUS-Statistics-Type", "Auth-Acct")
+	status.Add("Message-Authenticator", nil)
+	response, err := client.Exchange(status, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]uint32{
+		"FreeRADIUS-Total-Access-Requests":         3,
+		"FreeRADIUS-Total-Access-Accepts":          2,
+		"FreeRADIUS-Total-Access-Rejects":          1,
+		"FreeRADIUS-Total-Auth-Malformed-Requests": 1,
+		"FreeRADIUS-Total-Accounting-Responses":    1,
+	} {
+		if v, ok := response.Value(name).(uint32); !ok || v != value {
+			t.Errorf("expecting %s = %d, got %v", name, value, response.Value(name))
+		}
+	}
+	if stats := server.Stats(); stats != expected {
+		t.Fatalf("expecting Status-Server not to be counted, got %+v", stats)
+	}
+}
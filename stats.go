@@ -0,0 +1,250 @@
+package radius
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+// Counters are the packet counters of a kind of request: authentication or
+// accounting. Disconnect-Request and CoA-Request packets are counted as
+// authentication requests, with their ACKs as accepts and their NAKs as
+// rejects. Status-Server packets are not counted.
+type Counters struct {
+	// Requests is the number of requests passed to the Handler.
+	Requests uint64
+	// Responses is the number of responses sent, of which Accepts, Rejects
+	// and Challenges are the Access-Accept, Access-Reject and
+	// Access-Challenge responses.
+	Responses  uint64
+	Accepts    uint64
+	Rejects    uint64
+	Challenges uint64
+	// DuplicateRequests is the number of requests received while a request
+	// with the same client and identifier was being handled.
+	DuplicateRequests uint64
+	// MalformedRequests is the number of requests that could not be parsed.
+	MalformedRequests uint64
+	// InvalidRequests is the number of requests from unknown clients, or
+	// with an invalid Request Authenticator or Message-Authenticator.
+	InvalidRequests uint64
+	// DroppedRequests is the number of requests dropped by the server
+	// options, or that the Handler did not respond to.
+	DroppedRequests uint64
+	// UnknownTypes is the number of packets with a code that is not a
+	// request.
+	UnknownTypes uint64
+}
+
+// Stats are the counters of a Server, or of one of its clients.
+type Stats struct {
+	Auth Counters
+	Acct Counters
+}
+
+// counter identifies a field of Counters.
+type counter int
+
+const (
+	countRequests counter = iota
+	countResponses
+	countAccepts
+	countRejects
+	countChallenges
+	countDuplicateRequests
+	countMalformedRequests
+	countInvalidRequests
+	countDroppedRequests
+	countUnknownTypes
+)
+
+func (c *Counters) field(k counter) *uint64 {
+	switch k {
+	case countRequests:
+		return &c.Requests
+	case countResponses:
+		return &c.Responses
+	case countAccepts:
+		return &c.Accepts
+	case countRejects:
+		return &c.Rejects
+	case countChallenges:
+		return &c.Challenges
+	case countDuplicateRequests:
+		return &c.DuplicateRequests
+	case countMalformedRequests:
+		return &c.MalformedRequests
+	case countInvalidRequests:
+		return &c.InvalidRequests
+	case countDroppedRequests:
+		return &c.DroppedRequests
+	}
+	return &c.UnknownTypes
+}
+
+// counters returns the counters of packets with the given code.
+func (s *Stats) counters(code Code) *Counters {
+	if code == CodeAccountingRequest || code == CodeAccountingResponse {
+		return &s.Acct
+	}
+	return &s.Auth
+}
+
+// isRequest returns if packets with the code are requests handled by a
+// Server.
+func (c Code) isRequest() bool {
+	switch c {
+	case CodeAccessRequest, CodeAccountingRequest, CodeStatusServer, CodeDisconnectRequest, CodeCoARequest:
+		return true
+	}
+	return false
+}
+
+// serverStats holds the counters of a Server.
+type serverStats struct {
+	mu      sync.Mutex
+	start   time.Time
+	total   Stats
+	clients map[string]*Stats
+}
+
+// count increments the counters k of the server and, unless client is
+// empty, of the client with the given IP address, for packets with the given
+// code.
+func (s *Server) count(client string, code Code, k ...counter) {
+	st := &s.stats
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var clientStats *Stats
+	if client != "" {
+		clientStats = st.clients[client]
+		if clientStats == nil {
+			if st.clients == nil {
+				st.clients = make(map[string]*Stats)
+			}
+			clientStats = &Stats{}
+			st.clients[client] = clientStats
+		}
+	}
+	for _, k := range k {
+		*st.total.counters(code).field(k)++
+		if clientStats != nil {
+			*clientStats.counters(code).field(k)++
+		}
+	}
+}
+
+// countResponse counts a response to a request of the server.
+func (s *Server) countResponse(client string, code Code) {
+	switch code {
+	case CodeAccessAccept, CodeDisconnectACK, CodeCoAACK:
+		s.count(client, code, countResponses, countAccepts)
+	case CodeAccessReject, CodeDisconnectNAK, CodeCoANAK:
+		s.count(client, code, countResponses, countRejects)
+	case CodeAccessChallenge:
+		s.count(client, code, countResponses, countChallenges)
+	default:
+		s.count(client, code, countResponses)
+	}
+}
+
+// Stats returns the counters of the server.
+func (s *Server) Stats() Stats {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	return s.stats.total
+}
+
+// ClientStats returns the counters of the client with the given IP address.
+// ok is false if no packets were received from the client.
+func (s *Server) ClientStats(ip net.IP) (stats Stats, ok bool) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	if clientStats := s.stats.clients[ip.String()]; clientStats != nil {
+		return *clientStats, true
+	}
+	return
+}
+
+// statisticsAttributes returns the statistics selected by the
+// FreeRADIUS-Statistics-Type attribute of a Status-Server request, in the
+// format of the FreeRADIUS server. nil is returned if the request does not
+// have the attribute.
+func (s *Server) statisticsAttributes(request *Packet) []*Attribute {
+	statsType, ok := freeRADIUSInteger(request, 127)
+	if !ok {
+		return nil
+	}
+	attrs := []*Attribute{
+		{Vendor: VendorFreeRADIUS, Type: 127, Value: statsType},
+	}
+	add := func(t byte, value interface{}) {
+		attrs = append(attrs, &Attribute{Vendor: VendorFreeRADIUS, Type: t, Value: value})
+	}
+
+	stats := s.Stats()
+	if statsType&StatisticsClient != 0 {
+		var ip net.IP
+		for _, attr := range request.Attributes {
+			if attr.Vendor == VendorFreeRADIUS && attr.Type == 167 {
+				ip, _ = attr.Value.(net.IP)
+			}
+		}
+		clientStats, ok := s.ClientStats(ip)
+		if ip == nil || !ok {
+			return attrs
+		}
+		add(167, ip)
+		stats = clientStats
+	}
+
+	if statsType&StatisticsAuthentication != 0 {
+		c := stats.Auth
+		for i, n := range []uint64{
+			c.Requests, c.Accepts, c.Rejects, c.Challenges, c.Responses, c.DuplicateRequests,
+			c.MalformedRequests, c.InvalidRequests, c.DroppedRequests, c.UnknownTypes,
+		} {
+			add(128+byte(i), uint32(n))
+		}
+	}
+	if statsType&StatisticsAccounting != 0 {
+		c := stats.Acct
+		for i, n := range []uint64{
+			c.Requests, c.Responses, c.DuplicateRequests, c.MalformedRequests,
+			c.InvalidRequests, c.DroppedRequests, c.UnknownTypes,
+		} {
+			add(148+byte(i), uint32(n))
+		}
+	}
+	if statsType&StatisticsInternal != 0 {
+		s.stats.mu.Lock()
+		start := s.stats.start
+		s.stats.mu.Unlock()
+		add(176, start)
+		add(177, start)
+	}
+	return attrs
+}
+
+// freeRADIUSInteger returns the value of the first FreeRADIUS integer
+// attribute of type t in p, whether or not the attribute is registered in p's
+// Dictionary. The FreeRADIUS vendor itself must be registered: the
+// Vendor-Specific attributes of other vendors are not split into their
+// sub-attributes by Parse.
+func freeRADIUSInteger(p *Packet, t byte) (uint32, bool) {
+	for _, attr := range p.Attributes {
+		if attr.Vendor != VendorFreeRADIUS || attr.Type != t {
+			continue
+		}
+		switch value := attr.Value.(type) {
+		case uint32:
+			return value, true
+		case []byte:
+			if len(value) == 4 {
+				return binary.BigEndian.Uint32(value), true
+			}
+		}
+	}
+	return 0, false
+}
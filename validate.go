@@ -0,0 +1,385 @@
+package radius
+
+import (
+	"strconv"
+	"strings"
+)
+
+// quantity is the number of times an attribute may appear in a packet, as
+// given in the tables of RFC 2865 section 5.44, RFC 2866 section 5.13, RFC
+// 5176 section 3.5 and RFC 5997 section 6.
+type quantity struct {
+	Min int
+	// -1 if unlimited; otherwise at most 1
+	Max int
+}
+
+var (
+	// 0
+	qNone = quantity{0, 0}
+	// 0-1
+	qOptional = quantity{0, 1}
+	// 0+
+	qAny = quantity{0, -1}
+	// 1
+	qOne = quantity{1, 1}
+)
+
+// The quantities of the attributes of each code, by attribute type.
+// Attributes that are not listed are not checked. Vendor-Specific attributes
+// are never listed: vendors such as Microsoft (RFC 2548) send them in packets
+// that the tables do not permit them in.
+var attributeQuantities = map[Code]map[byte]quantity{}
+
+func init() {
+	// RFC 2865 section 5.44, with the attributes of RFC 3579 section 3.3.
+	access := []struct {
+		Type                               byte
+		Request, Accept, Reject, Challenge quantity
+	}{
+		{1, qOptional, qOptional, qNone, qNone},          // User-Name
+		{2, qOptional, qNone, qNone, qNone},              // User-Password
+		{3, qOptional, qNone, qNone, qNone},              // CHAP-Password
+		{4, qOptional, qNone, qNone, qNone},              // NAS-IP-Address
+		{5, qOptional, qNone, qNone, qNone},              // NAS-Port
+		{6, qOptional, qOptional, qNone, qNone},          // Service-Type
+		{7, qOptional, qOptional, qNone, qNone},          // Framed-Protocol
+		{8, qOptional, qOptional, qNone, qNone},          // Framed-IP-Address
+		{9, qOptional, qOptional, qNone, qNone},          // Framed-IP-Netmask
+		{10, qNone, qOptional, qNone, qNone},             // Framed-Routing
+		{11, qNone, qAny, qNone, qNone},                  // Filter-Id
+		{12, qNone, qOptional, qNone, qNone},             // Framed-MTU
+		{13, qAny, qAny, qNone, qNone},                   // Framed-Compression
+		{14, qAny, qAny, qNone, qNone},                   // Login-IP-Host
+		{15, qNone, qOptional, qNone, qNone},             // Login-Service
+		{16, qNone, qOptional, qNone, qNone},             // Login-TCP-Port
+		{18, qNone, qAny, qAny, qAny},                    // Reply-Message
+		{19, qOptional, qOptional, qNone, qNone},         // Callback-Number
+		{20, qNone, qOptional, qNone, qNone},             // Callback-Id
+		{22, qNone, qAny, qNone, qNone},                  // Framed-Route
+		{23, qNone, qOptional, qNone, qNone},             // Framed-IPX-Network
+		{24, qOptional, qOptional, qNone, qOptional},     // State
+		{25, qNone, qAny, qNone, qNone},                  // Class
+		{27, qNone, qOptional, qNone, qOptional},         // Session-Timeout
+		{28, qNone, qOptional, qNone, qOptional},         // Idle-Timeout
+		{29, qNone, qOptional, qNone, qNone},             // Termination-Action
+		{30, qOptional, qNone, qNone, qNone},             // Called-Station-Id
+		{31, qOptional, qNone, qNone, qNone},             // Calling-Station-Id
+		{32, qOptional, qNone, qNone, qNone},             // NAS-Identifier
+		{33, qAny, qAny, qAny, qAny},                     // Proxy-State
+		{34, qOptional, qOptional, qNone, qNone},         // Login-LAT-Service
+		{35, qOptional, qOptional, qNone, qNone},         // Login-LAT-Node
+		{36, qOptional, qOptional, qNone, qNone},         // Login-LAT-Group
+		{37, qNone, qOptional, qNone, qNone},             // Framed-AppleTalk-Link
+		{38, qNone, qAny, qNone, qNone},                  // Framed-AppleTalk-Network
+		{39, qNone, qOptional, qNone, qNone},             // Framed-AppleTalk-Zone
+		{60, qOptional, qNone, qNone, qNone},             // CHAP-Challenge
+		{61, qOptional, qNone, qNone, qNone},             // NAS-Port-Type
+		{62, qOptional, qOptional, qNone, qNone},         // Port-Limit
+		{63, qOptional, qOptional, qNone, qNone},         // Login-LAT-Port
+		{79, qAny, qAny, qAny, qAny},                     // EAP-Message
+		{80, qOptional, qOptional, qOptional, qOptional}, // Message-Authenticator
+	}
+	for _, code := range []Code{CodeAccessRequest, CodeAccessAccept, CodeAccessReject, CodeAccessChallenge} {
+		attributeQuantities[code] = make(map[byte]quantity)
+	}
+	for _, row := range access {
+		attributeQuantities[CodeAccessRequest][row.Type] = row.Request
+		attributeQuantities[CodeAccessAccept][row.Type] = row.Accept
+		attributeQuantities[CodeAccessReject][row.Type] = row.Reject
+		attributeQuantities[CodeAccessChallenge][row.Type] = row.Challenge
+	}
+
+	// RFC 2866 section 5.13
+	accounting := []struct {
+		Type              byte
+		Request, Response quantity
+	}{
+		{1, qOptional, qNone},  // User-Name
+		{2, qNone, qNone},      // User-Password
+		{3, qNone, qNone},      // CHAP-Password
+		{4, qOptional, qNone},  // NAS-IP-Address
+		{5, qOptional, qNone},  // NAS-Port
+		{6, qOptional, qNone},  // Service-Type
+		{7, qOptional, qNone},  // Framed-Protocol
+		{8, qOptional, qNone},  // Framed-IP-Address
+		{9, qOptional, qNone},  // Framed-IP-Netmask
+		{10, qOptional, qNone}, // Framed-Routing
+		{11, qAny, qNone},      // Filter-Id
+		{12, qOptional, qNone}, // Framed-MTU
+		{13, qAny, qNone},      // Framed-Compression
+		{14, qAny, qNone},      // Login-IP-Host
+		{15, qOptional, qNone}, // Login-Service
+		{16, qOptional, qNone}, // Login-TCP-Port
+		{18, qNone, qNone},     // Reply-Message
+		{19, qOptional, qNone}, // Callback-Number
+		{20, qOptional, qNone}, // Callback-Id
+		{22, qAny, qNone},      // Framed-Route
+		{23, qOptional, qNone}, // Framed-IPX-Network
+		{24, qNone, qNone},     // State
+		{25, qAny, qNone},      // Class
+		{27, qOptional, qNone}, // Session-Timeout
+		{28, qOptional, qNone}, // Idle-Timeout
+		{29, qOptional, qNone}, // Termination-Action
+		{30, qOptional, qNone}, // Called-Station-Id
+		{31, qOptional, qNone}, // Calling-Station-Id
+		{32, qOptional, qNone}, // NAS-Identifier
+		{33, qAny, qAny},       // Proxy-State
+		{34, qOptional, qNone}, // Login-LAT-Service
+		{35, qOptional, qNone}, // Login-LAT-Node
+		{36, qOptional, qNone}, // Login-LAT-Group
+		{37, qOptional, qNone}, // Framed-AppleTalk-Link
+		{38, qOptional, qNone}, // Framed-AppleTalk-Network
+		{39, qOptional, qNone}, // Framed-AppleTalk-Zone
+		{40, qOne, qNone},      // Acct-Status-Type
+		{41, qOptional, qNone}, // Acct-Delay-Time
+		{42, qOptional, qNone}, // Acct-Input-Octets
+		{43, qOptional, qNone}, // Acct-Output-Octets
+		{44, qOne, qNone},      // Acct-Session-Id
+		{45, qOptional, qNone}, // Acct-Authentic
+		{46, qOptional, qNone}, // Acct-Session-Time
+		{47, qOptional, qNone}, // Acct-Input-Packets
+		{48, qOptional, qNone}, // Acct-Output-Packets
+		{49, qOptional, qNone}, // Acct-Terminate-Cause
+		{50, qAny, qNone},      // Acct-Multi-Session-Id
+		{51, qAny, qNone},      // Acct-Link-Count
+		{60, qNone, qNone},     // CHAP-Challenge
+		{61, qOptional, qNone}, // NAS-Port-Type
+		{62, qOptional, qNone}, // Port-Limit
+		{63, qOptional, qNone}, // Login-LAT-Port
+	}
+	attributeQuantities[CodeAccountingRequest] = make(map[byte]quantity)
+	attributeQuantities[CodeAccountingResponse] = make(map[byte]quantity)
+	for _, row := range accounting {
+		attributeQuantities[CodeAccountingRequest][row.Type] = row.Request
+		attributeQuantities[CodeAccountingResponse][row.Type] = row.Response
+	}
+
+	type dynamicAuthorization []struct {
+		Type              byte
+		Request, ACK, NAK quantity
+	}
+	addDynamicAuthorization := func(request, ack, nak Code, rows dynamicAuthorization) {
+		attributeQuantities[request] = make(map[byte]quantity)
+		attributeQuantities[ack] = make(map[byte]quantity)
+		attributeQuantities[nak] = make(map[byte]quantity)
+		for _, row := range rows {
+			attributeQuantities[request][row.Type] = row.Request
+			attributeQuantities[ack][row.Type] = row.ACK
+			attributeQuantities[nak][row.Type] = row.NAK
+		}
+	}
+
+	// RFC 5176 section 3.5, Disconnect Messages
+	addDynamicAuthorization(CodeDisconnectRequest, CodeDisconnectACK, CodeDisconnectNAK, dynamicAuthorization{
+		{1, qOptional, qNone, qNone},          // User-Name
+		{2, qNone, qNone, qNone},              // User-Password
+		{3, qNone, qNone, qNone},              // CHAP-Password
+		{4, qOptional, qNone, qNone},          // NAS-IP-Address
+		{5, qOptional, qNone, qNone},          // NAS-Port
+		{6, qOptional, qNone, qOptional},      // Service-Type
+		{8, qOptional, qNone, qNone},          // Framed-IP-Address
+		{18, qAny, qNone, qNone},              // Reply-Message
+		{25, qAny, qNone, qNone},              // Class
+		{30, qOptional, qNone, qNone},         // Called-Station-Id
+		{31, qOptional, qNone, qNone},         // Calling-Station-Id
+		{32, qOptional, qNone, qNone},         // NAS-Identifier
+		{33, qAny, qAny, qAny},                // Proxy-State
+		{44, qOptional, qNone, qNone},         // Acct-Session-Id
+		{49, qOptional, qOptional, qNone},     // Acct-Terminate-Cause
+		{50, qOptional, qNone, qNone},         // Acct-Multi-Session-Id
+		{55, qOptional, qOptional, qOptional}, // Event-Timestamp
+		{61, qOptional, qNone, qNone},         // NAS-Port-Type
+		{79, qAny, qAny, qNone},               // EAP-Message
+		{80, qOptional, qOptional, qOptional}, // Message-Authenticator
+		{87, qOptional, qNone, qNone},         // NAS-Port-Id
+		{89, qOptional, qNone, qNone},         // Chargeable-User-Identity
+		{95, qOptional, qNone, qNone},         // NAS-IPv6-Address
+		{96, qOptional, qNone, qNone},         // Framed-Interface-Id
+		{97, qAny, qNone, qNone},              // Framed-IPv6-Prefix
+		{101, qNone, qAny, qAny},              // Error-Cause
+	})
+
+	// RFC 5176 section 3.5, Change-of-Authorization Messages
+	addDynamicAuthorization(CodeCoARequest, CodeCoAACK, CodeCoANAK, dynamicAuthorization{
+		{1, qOptional, qNone, qNone},          // User-Name
+		{2, qNone, qNone, qNone},              // User-Password
+		{3, qNone, qNone, qNone},              // CHAP-Password
+		{4, qOptional, qNone, qNone},          // NAS-IP-Address
+		{5, qOptional, qNone, qNone},          // NAS-Port
+		{6, qOptional, qNone, qOptional},      // Service-Type
+		{7, qOptional, qNone, qNone},          // Framed-Protocol
+		{8, qOptional, qNone, qNone},          // Framed-IP-Address
+		{9, qOptional, qNone, qNone},          // Framed-IP-Netmask
+		{10, qOptional, qNone, qNone},         // Framed-Routing
+		{11, qAny, qNone, qNone},              // Filter-Id
+		{12, qOptional, qNone, qNone},         // Framed-MTU
+		{13, qAny, qNone, qNone},              // Framed-Compression
+		{14, qAny, qNone, qNone},              // Login-IP-Host
+		{15, qOptional, qNone, qNone},         // Login-Service
+		{16, qOptional, qNone, qNone},         // Login-TCP-Port
+		{18, qAny, qNone, qNone},              // Reply-Message
+		{19, qOptional, qNone, qNone},         // Callback-Number
+		{20, qOptional, qNone, qNone},         // Callback-Id
+		{22, qAny, qNone, qNone},              // Framed-Route
+		{23, qOptional, qNone, qNone},         // Framed-IPX-Network
+		{24, qOptional, qOptional, qOptional}, // State
+		{25, qAny, qNone, qNone},              // Class
+		{27, qOptional, qNone, qNone},         // Session-Timeout
+		{28, qOptional, qNone, qNone},         // Idle-Timeout
+		{29, qOptional, qNone, qNone},         // Termination-Action
+		{30, qOptional, qNone, qNone},         // Called-Station-Id
+		{31, qOptional, qNone, qNone},         // Calling-Station-Id
+		{32, qOptional, qNone, qNone},         // NAS-Identifier
+		{33, qAny, qAny, qAny},                // Proxy-State
+		{34, qOptional, qNone, qNone},         // Login-LAT-Service
+		{35, qOptional, qNone, qNone},         // Login-LAT-Node
+		{36, qOptional, qNone, qNone},         // Login-LAT-Group
+		{37, qOptional, qNone, qNone},         // Framed-AppleTalk-Link
+		{38, qAny, qNone, qNone},              // Framed-AppleTalk-Network
+		{39, qOptional, qNone, qNone},         // Framed-AppleTalk-Zone
+		{44, qOptional, qNone, qNone},         // Acct-Session-Id
+		{50, qOptional, qNone, qNone},         // Acct-Multi-Session-Id
+		{55, qOptional, qOptional, qOptional}, // Event-Timestamp
+		{61, qOptional, qNone, qNone},         // NAS-Port-Type
+		{62, qOptional, qNone, qNone},         // Port-Limit
+		{63, qOptional, qNone, qNone},         // Login-LAT-Port
+		{79, qAny, qAny, qNone},               // EAP-Message
+		{80, qOptional, qOptional, qOptional}, // Message-Authenticator
+		{87, qOptional, qNone, qNone},         // NAS-Port-Id
+		{89, qOptional, qNone, qNone},         // Chargeable-User-Identity
+		{95, qOptional, qNone, qNone},         // NAS-IPv6-Address
+		{96, qOptional, qNone, qNone},         // Framed-Interface-Id
+		{97, qAny, qNone, qNone},              // Framed-IPv6-Prefix
+		{98, qAny, qNone, qNone},              // Login-IPv6-Host
+		{99, qAny, qNone, qNone},              // Framed-IPv6-Route
+		{100, qOptional, qNone, qNone},        // Framed-IPv6-Pool
+		{101, qNone, qNone, qAny},             // Error-Cause
+		{123, qAny, qNone, qNone},             // Delegated-IPv6-Prefix
+	})
+
+	// RFC 5997 section 6. The responses are Access-Accept and
+	// Accounting-Response packets, which are checked with the tables above.
+	statusServer := []struct {
+		Type    byte
+		Request quantity
+	}{
+		{1, qOptional},  // User-Name
+		{2, qNone},      // User-Password
+		{3, qNone},      // CHAP-Password
+		{4, qOptional},  // NAS-IP-Address
+		{32, qOptional}, // NAS-Identifier
+		{79, qNone},     // EAP-Message
+		{80, qOne},      // Message-Authenticator
+		{95, qOptional}, // NAS-IPv6-Address
+	}
+	attributeQuantities[CodeStatusServer] = make(map[byte]quantity)
+	for _, row := range statusServer {
+		attributeQuantities[CodeStatusServer][row.Type] = row.Request
+	}
+}
+
+// ValidationError is returned by Packet.Validate for packets whose attributes
+// are not permitted in a packet of their code. It lists every violation.
+type ValidationError struct {
+	Code       Code
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return "radius: invalid " + e.Code.String() + ": " + strings.Join(e.Violations, "; ")
+}
+
+// Validate checks that the attributes of p may appear in a packet of its
+// code, in the quantities given by the tables of RFC 2865 section 5.44, RFC
+// 2866 section 5.13, RFC 5176 section 3.5 (Disconnect and CoA) and RFC 5997
+// section 6 (Status-Server). In addition, an Access-Request must
+// contain exactly one set of credentials: a User-Password, a CHAP-Password,
+// an EAP-Message, or an MS-CHAP-Response or MS-CHAP2-Response (RFC 2548), and
+// a Message-Authenticator if it contains an EAP-Message. Access-Request and
+// Accounting-Request packets must also contain one of NAS-IP-Address,
+// NAS-IPv6-Address and NAS-Identifier.
+//
+// Attributes which are not listed in the tables, and Vendor-Specific
+// attributes, whether of a registered vendor or not, are not checked. If p is
+// not valid, a *ValidationError listing every violation is returned.
+//
+// Validation is optional: neither Parse nor Encode call Validate. Servers
+// validate requests if Server.ValidateRequests is set.
+func (p *Packet) Validate() error {
+	counts := make(map[byte]int)
+	msCHAP := false
+	for _, attr := range p.Attributes {
+		switch {
+		case attr.Vendor == VendorMicrosoft:
+			// MS-CHAP-Response, MS-CHAP2-Response
+			msCHAP = msCHAP || attr.Type == 1 || attr.Type == 25
+		case attr.Vendor == 0 && attr.Type != 26 && !isExtendedType(attr.Type):
+			// Vendor-Specific attributes of unregistered vendors are
+			// not decoded, and skipped like the decoded ones.
+			counts[attr.Type]++
+		}
+	}
+
+	var violations []string
+	name := func(t byte) string {
+		if name, ok := p.Dictionary.Name(t); ok {
+			return name
+		}
+		return "attribute " + strconv.Itoa(int(t))
+	}
+
+	quantities := attributeQuantities[p.Code]
+	for t := 0; t < 256; t++ {
+		q, ok := quantities[byte(t)]
+		if !ok {
+			continue
+		}
+		n := counts[byte(t)]
+		switch {
+		case n > 0 && q.Max == 0:
+			violations = append(violations, name(byte(t))+" is not permitted")
+		case n < q.Min:
+			violations = append(violations, name(byte(t))+" is required")
+		case q.Max > 0 && n > q.Max:
+			violations = append(violations, name(byte(t))+" must not appear more than once")
+		}
+	}
+
+	if p.Code == CodeAccessRequest {
+		// RFC 2865 section 4.1, RFC 3579 section 3.1
+		credentials := 0
+		for _, t := range []byte{2, 3, 79} {
+			if counts[t] > 0 {
+				credentials++
+			}
+		}
+		if msCHAP {
+			// RFC 2548 section 2.1
+			credentials++
+		}
+		if credentials != 1 {
+			violations = append(violations, "exactly one of User-Password, CHAP-Password, EAP-Message and MS-CHAP-Response or MS-CHAP2-Response is required")
+		}
+		// RFC 3579 section 3.3
+		if counts[79] > 0 && counts[attrMessageAuthenticator] == 0 {
+			violations = append(violations, "Message-Authenticator is required with EAP-Message")
+		}
+	}
+
+	if p.Code == CodeAccessRequest || p.Code == CodeAccountingRequest {
+		// RFC 2865 section 5.32, RFC 2866 section 4.1, RFC 3162 section
+		// 2.1
+		if counts[4] == 0 && counts[95] == 0 && counts[32] == 0 {
+			violations = append(violations, "NAS-IP-Address, NAS-IPv6-Address or NAS-Identifier is required")
+		}
+	}
+
+	if violations != nil {
+		return &ValidationError{
+			Code:       p.Code,
+			Violations: violations,
+		}
+	}
+	return nil
+}